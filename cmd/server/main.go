@@ -1,24 +1,71 @@
 package main
 
 import (
-	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/shivakuppa/Go_Redis/config"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 	"github.com/shivakuppa/Go_Redis/internals/server"
 )
 
+// Usage: server [/path/to/redis.conf] [--directive arg ...]
 func main() {
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
 
-	port := flag.String("port", "6379", "Port to start the Redis server on")
-	flag.Parse()
+	path, overrides, err := config.ParseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Usage: server [/path/to/redis.conf] [--directive arg ...]")
+		os.Exit(1)
+	}
+	if path == "" {
+		slog.Warn("No config file specified, using the default config")
+	}
+	conf, err := config.Load(path, overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot load the configuration:", err)
+		os.Exit(1)
+	}
+
+	// Only settings that cannot carry secrets are logged: requirepass and
+	// user directives hold passwords.
+	slog.Info("Configuration loaded",
+		"file", conf.File,
+		"dir", conf.Dir,
+		"dbfilename", conf.RDBfn,
+		"appendonly", conf.AOFenabled,
+		"io-model", conf.IOModel,
+		"port", conf.Port,
+		"tls-port", conf.TLSPort,
+	)
 
-	conf := config.ReadConfig("./config/redis.conf")
 	state := db.NewAppState(conf)
 
-	s := server.NewServer(":" + *port)
-	s.Start(state)
+	go handleSignals(state)
+
+	s := server.NewServer()
+	if err := s.Start(state); err != nil {
+		os.Exit(1)
+	}
+}
+
+// handleSignals shuts the server down on SIGINT or SIGTERM, with a final
+// save as SHUTDOWN does. If that fails the server keeps running; a second
+// signal then exits regardless.
+func handleSignals(state *db.AppState) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	force := false
+	for sig := range sigs {
+		slog.Info("Received signal, scheduling shutdown", "signal", sig.String())
+		if err := <-state.Shutdown.Request(db.ShutdownOptions{Force: force}); err != nil {
+			slog.Error("Errors trying to shut down the server, send the signal again to exit without saving", "error", err)
+			force = true
+		}
+	}
 }
@@ -1,24 +1,74 @@
 package config
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
 	"strings"
 )
 
 type Config struct {
-	Dir        	string
-	RDB        	[]RDBSnapshot
-	RDBfn      	string
-	AOFenabled 	bool
-	AOFfn      	string
-	AOFfsync   	FSyncMode
-	Requirepass	bool
-	Password 	string
+	// File is the configuration file the server was started with, which
+	// CONFIG REWRITE updates. It is empty if there was none.
+	File string
+
+	Dir            string
+	RDB            []RDBSnapshot
+	RDBfn          string
+	RDBcompression bool
+	RDBchecksum    bool
+	AOFenabled     bool
+	AOFfn          string
+	AOFfsync       FSyncMode
+	Requirepass    bool
+	Password       string
+
+	// Users holds the "user <name> <rules...>" directives, name first.
+	Users        [][]string
+	ACLFile      string
+	ACLLogMaxLen int
+
+	ProtoMaxBulkLen      int64
+	ProtoMaxMultibulkLen int64
+
+	IOModel   IOModel
+	IOThreads int
+
+	// Bind lists the addresses to listen on. "*" is every IPv4 address
+	// and "::*" every IPv6 one; a "-" prefix marks an address that is
+	// skipped if it is unavailable.
+	Bind []string
+	// Port is the TCP port for plaintext clients; 0 disables it.
+	Port int
+	// ProtectedMode refuses clients from other hosts while the default
+	// user has no password.
+	ProtectedMode bool
+
+	// UnixSocket is the path of a unix socket to listen on, next to TCP.
+	UnixSocket     string
+	UnixSocketPerm os.FileMode
+
+	// TLSPort enables a TLS listener next to the plaintext one when non-zero.
+	TLSPort        int
+	TLSCertFile    string
+	TLSKeyFile     string
+	TLSCACertFile  string
+	TLSAuthClients TLSAuthClients
+	TLSProtocols   string // space separated, e.g. "TLSv1.2 TLSv1.3"
+	TLSCiphers     string // colon separated TLS 1.2 cipher suite names
 }
 
+// TLSAuthClients selects whether TLS clients must present a certificate.
+type TLSAuthClients string
+
+const (
+	TLSAuthYes      TLSAuthClients = "yes"
+	TLSAuthNo       TLSAuthClients = "no"
+	TLSAuthOptional TLSAuthClients = "optional"
+)
+
 type RDBSnapshot struct {
 	Secs        int
 	KeysChanged int
@@ -32,83 +82,109 @@ const (
 	No       FSyncMode = "no"
 )
 
+// IOModel selects how client connections are served.
+type IOModel string
+
+const (
+	// GoroutineIO serves each connection from its own goroutine.
+	GoroutineIO IOModel = "goroutine"
+	// ReactorIO runs every command on a single event loop (Linux only).
+	ReactorIO IOModel = "reactor"
+)
+
 func NewConfig() *Config {
-	return &Config{}
-}
+	return &Config{
+		Bind:          []string{"*", "-::*"},
+		Port:          6379,
+		ProtectedMode: true,
 
-func ReadConfig(fn string) *Config {
-	config := NewConfig()
+		RDBfn:          "dump.rdb",
+		RDBcompression: true,
+		RDBchecksum:    true,
+		AOFfn:          "appendonly.aof",
+		AOFfsync:       EverySec,
 
-	file, err := os.Open(fn)
-	if err != nil {
-		fmt.Printf("cannot read file: %s - using default config\n", fn)
-		return config
-	}
+		ProtoMaxBulkLen:      512 * 1024 * 1024,
+		ProtoMaxMultibulkLen: 1024 * 1024,
 
-	scan := bufio.NewScanner(file)
+		IOModel:   GoroutineIO,
+		IOThreads: 1,
 
-	for scan.Scan() {
-		line := scan.Text()
-		parseLine(line, config)
-	}
+		ACLLogMaxLen: 128,
 
-	if err := scan.Err(); err != nil {
-		fmt.Println("error scanning config file: ", err)
-		return config
+		TLSAuthClients: TLSAuthYes,
 	}
+}
 
-	if config.Dir != "" {
-		os.MkdirAll(config.Dir, 0755)
+// ParseTLSAuthClients parses a tls-auth-clients value: yes, no or optional.
+func ParseTLSAuthClients(s string) (TLSAuthClients, error) {
+	switch mode := TLSAuthClients(strings.ToLower(s)); mode {
+	case TLSAuthYes, TLSAuthNo, TLSAuthOptional:
+		return mode, nil
 	}
-
-	fmt.Printf("%+v\n", config)
-
-	return config
+	return "", errors.New("argument must be 'yes', 'no' or 'optional'")
 }
 
-func parseLine(line string, config *Config) {
-	args := strings.Split(line, " ")
-	cmd := args[0]
-	switch cmd {
-	case "save":
-		secs, err := strconv.Atoi(args[1])
-		if err != nil {
-			fmt.Println("invalid secs")
-			return
-		}
+// ParseSaveRules parses "<seconds> <changes> [<seconds> <changes> ...]".
+// An empty string yields no rules, which disables snapshotting.
+func ParseSaveRules(s string) ([]RDBSnapshot, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid save parameters")
+	}
 
-		keysChanged, err := strconv.Atoi(args[2])
-		if err != nil {
-			fmt.Println("invalid keys")
-			return
+	rules := make([]RDBSnapshot, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		secs, err := strconv.Atoi(fields[i])
+		if err != nil || secs < 1 {
+			return nil, fmt.Errorf("invalid save seconds %q", fields[i])
 		}
-
-		snapshot := RDBSnapshot{
-			Secs:        secs,
-			KeysChanged: keysChanged,
+		changes, err := strconv.Atoi(fields[i+1])
+		if err != nil || changes < 0 {
+			return nil, fmt.Errorf("invalid save changes %q", fields[i+1])
 		}
-		config.RDB = append(config.RDB, snapshot)
-
-	case "dbfilename":
-		config.RDBfn = args[1]
-
-	case "appendfilename":
-		config.AOFfn = args[1]
+		rules = append(rules, RDBSnapshot{Secs: secs, KeysChanged: changes})
+	}
+	return rules, nil
+}
 
-	case "appendfsync":
-		config.AOFfsync = FSyncMode(args[1])
+// FormatSaveRules is the inverse of ParseSaveRules.
+func FormatSaveRules(rules []RDBSnapshot) string {
+	parts := make([]string, 0, 2*len(rules))
+	for _, r := range rules {
+		parts = append(parts, strconv.Itoa(r.Secs), strconv.Itoa(r.KeysChanged))
+	}
+	return strings.Join(parts, " ")
+}
 
-	case "dir":
-		config.Dir = args[1]
+// ParseMemory parses a size such as "512mb" or "1g". As in redis.conf,
+// k/m/g are powers of 1000 and kb/mb/gb are powers of 1024.
+func ParseMemory(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+		{"b", 1},
+	}
 
-	case "appendonly":
-		if args[1] == "yes" {
-			config.AOFenabled = true
-		} else {
-			config.AOFenabled = false
+	lower := strings.ToLower(s)
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(lower, u.suffix) {
+			lower = strings.TrimSuffix(lower, u.suffix)
+			mul = u.mul
+			break
 		}
-	case "requirepass":
-		config.Requirepass = true
-		config.Password = args[1]
 	}
+
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory value %q", s)
+	}
+	if n > math.MaxInt64/mul || n < math.MinInt64/mul {
+		return 0, fmt.Errorf("memory value %q is out of range", s)
+	}
+	return n * mul, nil
 }
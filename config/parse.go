@@ -0,0 +1,428 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/splitargs"
+)
+
+// maxIncludeDepth bounds nested include directives, which also stops
+// include cycles.
+const maxIncludeDepth = 16
+
+// directive parses one configuration directive. Repeating a directive
+// replaces its value, except for save and user, whose lines accumulate.
+type directive struct {
+	minArgs, maxArgs int // maxArgs < 0 means no limit
+	apply            func(c *Config, args []string) error
+	// get returns the arguments that reproduce the current value, for
+	// CONFIG GET and CONFIG REWRITE. It is nil for user, which is not a
+	// parameter.
+	get func(c *Config) []string
+	// immutable parameters can only be set at startup.
+	immutable bool
+}
+
+// fixed marks d as immutable.
+func (d directive) fixed() directive {
+	d.immutable = true
+	return d
+}
+
+func stringParam(field func(c *Config) *string) directive {
+	return directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error { *field(c) = a[0]; return nil },
+		get:   func(c *Config) []string { return []string{*field(c)} },
+	}
+}
+
+func boolParam(field func(c *Config) *bool) directive {
+	return directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error { return setYesNo(field(c), a[0]) },
+		get: func(c *Config) []string {
+			if *field(c) {
+				return []string{"yes"}
+			}
+			return []string{"no"}
+		},
+	}
+}
+
+func intParam(field func(c *Config) *int, lo, hi int) directive {
+	return directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error { return setInt(field(c), a[0], lo, hi) },
+		get:   func(c *Config) []string { return []string{strconv.Itoa(*field(c))} },
+	}
+}
+
+func memoryParam(field func(c *Config) *int64, lo int64) directive {
+	return directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error { return setMemory(field(c), a[0], lo) },
+		get:   func(c *Config) []string { return []string{strconv.FormatInt(*field(c), 10)} },
+	}
+}
+
+func filenameParam(name string, field func(c *Config) *string) directive {
+	d := stringParam(field)
+	d.apply = func(c *Config, a []string) error { return setFilename(field(c), name, a[0]) }
+	return d
+}
+
+var directives = map[string]directive{
+	// Persistence
+	"dir":            stringParam(func(c *Config) *string { return &c.Dir }).fixed(),
+	"dbfilename":     filenameParam("dbfilename", func(c *Config) *string { return &c.RDBfn }),
+	"rdbcompression": boolParam(func(c *Config) *bool { return &c.RDBcompression }),
+	"rdbchecksum":    boolParam(func(c *Config) *bool { return &c.RDBchecksum }),
+	"save": {
+		minArgs: 1, maxArgs: -1,
+		apply: func(c *Config, a []string) error {
+			// save "" disables snapshotting, dropping the lines before it.
+			if len(a) == 1 && a[0] == "" {
+				c.RDB = nil
+				return nil
+			}
+			rules, err := ParseSaveRules(strings.Join(a, " "))
+			if err != nil {
+				return err
+			}
+			c.RDB = append(c.RDB, rules...)
+			return nil
+		},
+		get: func(c *Config) []string {
+			if len(c.RDB) == 0 {
+				return []string{""}
+			}
+			return strings.Fields(FormatSaveRules(c.RDB))
+		},
+	},
+	"appendonly":     boolParam(func(c *Config) *bool { return &c.AOFenabled }),
+	"appendfilename": filenameParam("appendfilename", func(c *Config) *string { return &c.AOFfn }).fixed(),
+	"appendfsync": {
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			switch mode := FSyncMode(strings.ToLower(a[0])); mode {
+			case Always, EverySec, No:
+				c.AOFfsync = mode
+				return nil
+			}
+			return errors.New("argument must be 'always', 'everysec' or 'no'")
+		},
+		get: func(c *Config) []string { return []string{string(c.AOFfsync)} },
+	},
+
+	// Network
+	"bind": directive{
+		minArgs: 1, maxArgs: -1,
+		apply: func(c *Config, a []string) error { c.Bind = a; return nil },
+		get:   func(c *Config) []string { return c.Bind },
+	}.fixed(),
+	"port":           intParam(func(c *Config) *int { return &c.Port }, 0, 65535).fixed(),
+	"protected-mode": boolParam(func(c *Config) *bool { return &c.ProtectedMode }),
+	"unixsocket":     stringParam(func(c *Config) *string { return &c.UnixSocket }).fixed(),
+	"unixsocketperm": directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			perm, err := strconv.ParseUint(a[0], 8, 32)
+			if err != nil || perm > 0o777 {
+				return errors.New("invalid socket file permissions")
+			}
+			c.UnixSocketPerm = os.FileMode(perm)
+			return nil
+		},
+		get: func(c *Config) []string { return []string{strconv.FormatUint(uint64(c.UnixSocketPerm), 8)} },
+	}.fixed(),
+	"proto-max-bulk-len": memoryParam(func(c *Config) *int64 { return &c.ProtoMaxBulkLen }, 1),
+	"proto-max-multibulk-len": {
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			n, err := strconv.ParseInt(a[0], 10, 64)
+			if err != nil || n < 1 {
+				return errors.New("argument must be a positive integer")
+			}
+			c.ProtoMaxMultibulkLen = n
+			return nil
+		},
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.ProtoMaxMultibulkLen, 10)} },
+	},
+	"io-model": directive{
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			switch m := IOModel(strings.ToLower(a[0])); m {
+			case GoroutineIO, ReactorIO:
+				c.IOModel = m
+				return nil
+			}
+			return errors.New("argument must be 'goroutine' or 'reactor'")
+		},
+		get: func(c *Config) []string { return []string{string(c.IOModel)} },
+	}.fixed(),
+	"io-threads": intParam(func(c *Config) *int { return &c.IOThreads }, 1, 128).fixed(),
+
+	// TLS
+	"tls-port":         intParam(func(c *Config) *int { return &c.TLSPort }, 0, 65535).fixed(),
+	"tls-cert-file":    stringParam(func(c *Config) *string { return &c.TLSCertFile }),
+	"tls-key-file":     stringParam(func(c *Config) *string { return &c.TLSKeyFile }),
+	"tls-ca-cert-file": stringParam(func(c *Config) *string { return &c.TLSCACertFile }),
+	"tls-auth-clients": {
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			mode, err := ParseTLSAuthClients(a[0])
+			if err == nil {
+				c.TLSAuthClients = mode
+			}
+			return err
+		},
+		get: func(c *Config) []string { return []string{string(c.TLSAuthClients)} },
+	},
+	"tls-protocols": {
+		minArgs: 1, maxArgs: -1,
+		apply: func(c *Config, a []string) error { c.TLSProtocols = strings.Join(a, " "); return nil },
+		get:   func(c *Config) []string { return []string{c.TLSProtocols} },
+	},
+	"tls-ciphers": stringParam(func(c *Config) *string { return &c.TLSCiphers }),
+
+	// Security
+	"requirepass": {
+		minArgs: 1, maxArgs: 1,
+		apply: func(c *Config, a []string) error {
+			c.Password = a[0]
+			c.Requirepass = a[0] != ""
+			return nil
+		},
+		get: func(c *Config) []string { return []string{c.Password} },
+	},
+	"user": {
+		minArgs: 1, maxArgs: -1,
+		apply: func(c *Config, a []string) error { c.Users = append(c.Users, a); return nil },
+	},
+	"aclfile":        stringParam(func(c *Config) *string { return &c.ACLFile }).fixed(),
+	"acllog-max-len": intParam(func(c *Config) *int { return &c.ACLLogMaxLen }, 0, 1<<31-1),
+}
+
+// Errors returned by Set.
+var (
+	ErrUnknownParam   = errors.New("unknown parameter")
+	ErrImmutableParam = errors.New("can't set immutable config")
+)
+
+// Params returns the names of the parameters CONFIG GET and SET know,
+// sorted.
+func Params() []string {
+	names := make([]string, 0, len(directives))
+	for name, d := range directives {
+		if d.get != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Get returns the current value of parameter name as CONFIG GET shows it.
+func (c *Config) Get(name string) (string, bool) {
+	d, ok := directives[strings.ToLower(name)]
+	if !ok || d.get == nil {
+		return "", false
+	}
+	return strings.Join(d.get(c), " "), true
+}
+
+// Set changes parameter name at runtime, as CONFIG SET does. Parameters
+// taking several arguments, such as save, take them space separated in
+// value, and replace the current value rather than add to it.
+func (c *Config) Set(name, value string) error {
+	name = strings.ToLower(name)
+	d, ok := directives[name]
+	if !ok || d.get == nil {
+		return ErrUnknownParam
+	}
+	if d.immutable {
+		return ErrImmutableParam
+	}
+
+	args := []string{value}
+	if d.maxArgs != 1 && value != "" {
+		args = strings.Fields(value)
+	}
+	old := c.Clone()
+	if name == "save" {
+		c.RDB = nil
+	}
+	if err := d.apply(c, args); err != nil {
+		*c = *old
+		return err
+	}
+	return nil
+}
+
+// Clone returns a copy of c that shares no slices with it.
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.RDB = slices.Clone(c.RDB)
+	clone.Bind = slices.Clone(c.Bind)
+	clone.Users = slices.Clone(c.Users)
+	return &clone
+}
+
+// Load reads the configuration file at path, if path is not empty, then
+// applies overrides, each a directive name followed by its arguments, as
+// given on the command line. Errors name the file and line at fault.
+func Load(path string, overrides [][]string) (*Config, error) {
+	c := NewConfig()
+	if path != "" {
+		if err := c.loadFile(path, 0); err != nil {
+			return nil, err
+		}
+		c.File = path
+	}
+	for _, o := range overrides {
+		if err := c.Apply(o[0], o[1:]...); err != nil {
+			return nil, fmt.Errorf("command line: --%s: %w", o[0], err)
+		}
+	}
+
+	if c.Dir != "" {
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Apply sets the directive name from its arguments.
+func (c *Config) Apply(name string, args ...string) error {
+	name = strings.ToLower(name)
+	d, ok := directives[name]
+	if !ok {
+		return fmt.Errorf("unknown directive '%s'", name)
+	}
+	if len(args) < d.minArgs || (d.maxArgs >= 0 && len(args) > d.maxArgs) {
+		return fmt.Errorf("wrong number of arguments for '%s'", name)
+	}
+	if err := d.apply(c, args); err != nil {
+		return fmt.Errorf("invalid '%s': %w", name, err)
+	}
+	return nil
+}
+
+func (c *Config) loadFile(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d deep", path, maxIncludeDepth)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scan := bufio.NewScanner(file)
+	scan.Buffer(nil, 1024*1024)
+	for n := 1; scan.Scan(); n++ {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		args, err := splitargs.Split(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if strings.EqualFold(args[0], "include") {
+			if len(args) != 2 {
+				return fmt.Errorf("%s:%d: wrong number of arguments for 'include'", path, n)
+			}
+			if err := c.loadFile(includePath(path, args[1]), depth+1); err != nil {
+				return fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			continue
+		}
+		if err := c.Apply(args[0], args[1:]...); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// includePath resolves an include relative to the including file.
+func includePath(from, include string) string {
+	if filepath.IsAbs(include) {
+		return include
+	}
+	return filepath.Join(filepath.Dir(from), include)
+}
+
+// ParseArgs splits the server's command line into the configuration file,
+// which comes first if given, and "--directive arg..." overrides.
+func ParseArgs(args []string) (path string, overrides [][]string, err error) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		path, args = args[0], args[1:]
+	}
+	for _, arg := range args {
+		if name, ok := strings.CutPrefix(arg, "--"); ok && name != "" {
+			overrides = append(overrides, []string{name})
+			continue
+		}
+		if len(overrides) == 0 {
+			return "", nil, fmt.Errorf("unexpected argument %q, expected --directive", arg)
+		}
+		last := len(overrides) - 1
+		overrides[last] = append(overrides[last], arg)
+	}
+	return path, overrides, nil
+}
+
+func setYesNo(dst *bool, s string) error {
+	switch strings.ToLower(s) {
+	case "yes":
+		*dst = true
+	case "no":
+		*dst = false
+	default:
+		return errors.New("argument must be 'yes' or 'no'")
+	}
+	return nil
+}
+
+func setInt(dst *int, s string, lo, hi int) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return fmt.Errorf("argument must be between %d and %d", lo, hi)
+	}
+	*dst = n
+	return nil
+}
+
+func setMemory(dst *int64, s string, lo int64) error {
+	n, err := ParseMemory(s)
+	if err != nil {
+		return err
+	}
+	if n < lo {
+		return fmt.Errorf("argument must be at least %d", lo)
+	}
+	*dst = n
+	return nil
+}
+
+func setFilename(dst *string, name, s string) error {
+	if strings.ContainsRune(s, filepath.Separator) || s == "" {
+		return fmt.Errorf("%s can't be a path, just a filename", name)
+	}
+	*dst = s
+	return nil
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/splitargs"
+)
+
+// rewriteMarker precedes the directives CONFIG REWRITE appends.
+const rewriteMarker = "# Generated by CONFIG REWRITE"
+
+// Rewrite updates the configuration file c was loaded from to c's current
+// settings. Comments, blank lines and includes are kept. The first line of
+// each directive is replaced by the current value and its other lines are
+// dropped; settings that differ from the defaults but have no line yet
+// are appended. The users written are c.Users.
+func Rewrite(c *Config) error {
+	if c.File == "" {
+		return errors.New("the server is running without a config file")
+	}
+
+	var lines []string
+	data, err := os.ReadFile(c.File)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	written := map[string]bool{}
+	marked := false
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		marked = marked || trimmed == rewriteMarker
+		if trimmed == "" || trimmed[0] == '#' {
+			out = append(out, line)
+			continue
+		}
+		args, err := splitargs.Split(trimmed)
+		if err != nil {
+			out = append(out, line)
+			continue
+		}
+		name := strings.ToLower(args[0])
+		if _, ok := directives[name]; !ok {
+			out = append(out, line)
+			continue
+		}
+		if !written[name] {
+			out = append(out, c.directiveLines(name)...)
+			written[name] = true
+		}
+	}
+
+	defaults := NewConfig()
+	var added []string
+	for _, name := range append(Params(), "user") {
+		if written[name] {
+			continue
+		}
+		if name != "user" {
+			cur, _ := c.Get(name)
+			if def, _ := defaults.Get(name); cur == def {
+				continue
+			}
+		}
+		added = append(added, c.directiveLines(name)...)
+	}
+	if len(added) > 0 && !marked {
+		out = append(out, rewriteMarker)
+	}
+	out = append(out, added...)
+
+	return writeFileAtomic(c.File, []byte(strings.Join(out, "\n")+"\n"))
+}
+
+// directiveLines returns the lines that set name to its current value.
+func (c *Config) directiveLines(name string) []string {
+	if name == "user" {
+		lines := make([]string, 0, len(c.Users))
+		for _, u := range c.Users {
+			lines = append(lines, formatLine("user", u))
+		}
+		return lines
+	}
+	return []string{formatLine(name, directives[name].get(c))}
+}
+
+func formatLine(name string, args []string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		b.WriteString(quoteArg(arg))
+	}
+	return b.String()
+}
+
+// quoteArg quotes arg so that splitargs.Split reads it back unchanged.
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\r\n\"'\\") && isPrintable(arg) {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(arg); i++ {
+		switch ch := arg[i]; ch {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if ch < 0x20 || ch >= 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, ch)
+			} else {
+				b.WriteByte(ch)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isPrintable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// writeFileAtomic replaces path with data, so that a crash leaves either
+// the old or the new file.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+
+	w := bufio.NewWriter(tmp)
+	if _, err := w.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,151 @@
+// Package acl implements Redis access control lists: users, their
+// passwords, and the commands, keys and channels each may use.
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// DefaultUser is the user connections are authenticated as until they AUTH.
+const DefaultUser = "default"
+
+var (
+	ErrDefaultUser = errors.New("ERR The 'default' user cannot be removed")
+	ErrWrongPass   = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+)
+
+// KnownCommand reports whether a +command or -command rule names an
+// existing command, in "get" or "config|get" form. It is set by the package
+// that defines the commands.
+var KnownCommand = func(name string) bool { return true }
+
+// HashPassword returns the form passwords are stored in: hex-encoded SHA-256.
+func HashPassword(pass string) string {
+	sum := sha256.Sum256([]byte(pass))
+	return hex.EncodeToString(sum[:])
+}
+
+// ACL is the set of users. Users are never modified in place: SetUser
+// builds a new one and swaps it in, so a *User obtained from User can be
+// used without locking.
+type ACL struct {
+	mu    sync.RWMutex
+	users map[string]*User
+
+	Log *Log
+}
+
+// New returns an ACL holding only the default user, which may run every
+// command on every key and channel without a password.
+func New() *ACL {
+	a := &ACL{users: map[string]*User{}, Log: NewLog(DefaultLogMaxLen)}
+	a.users[DefaultUser] = newDefaultUser()
+	return a
+}
+
+func newDefaultUser() *User {
+	u := NewUser(DefaultUser)
+	for _, rule := range []string{"on", "nopass", "allkeys", "allchannels", "allcommands"} {
+		_ = u.apply(rule)
+	}
+	return u
+}
+
+// User returns the named user, or nil.
+func (a *ACL) User(name string) *User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.users[name]
+}
+
+// Users returns the users sorted by name.
+func (a *ACL) Users() []*User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	users := make([]*User, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(x, y *User) int {
+		switch {
+		case x.Name < y.Name:
+			return -1
+		case x.Name > y.Name:
+			return 1
+		}
+		return 0
+	})
+	return users
+}
+
+// SetUser applies rules to the named user, creating it if needed. Either
+// every rule applies or, on error, the user is left as it was.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, ok := a.users[name]
+	if ok {
+		u = u.clone()
+	} else {
+		u = NewUser(name)
+	}
+	if err := u.Apply(rules...); err != nil {
+		return err
+	}
+	a.users[name] = u
+	return nil
+}
+
+// DelUser removes the named users and returns how many existed.
+func (a *ACL) DelUser(names ...string) (int, error) {
+	if slices.Contains(names, DefaultUser) {
+		return 0, ErrDefaultUser
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := 0
+	for _, name := range names {
+		if _, ok := a.users[name]; ok {
+			delete(a.users, name)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// replace swaps in a whole new set of users, as ACL LOAD does. The default
+// user is created if users lacks it.
+func (a *ACL) replace(users map[string]*User) {
+	if _, ok := users[DefaultUser]; !ok {
+		users[DefaultUser] = newDefaultUser()
+	}
+	a.mu.Lock()
+	a.users = users
+	a.mu.Unlock()
+}
+
+// Authenticate returns the named user if it is enabled and pass is one of
+// its passwords, or it has none because of nopass.
+func (a *ACL) Authenticate(name, pass string) (*User, error) {
+	u := a.User(name)
+	if u == nil || !u.Enabled || !u.checkPassword(pass) {
+		return nil, ErrWrongPass
+	}
+	return u, nil
+}
+
+// RequiresAuth reports whether new connections must authenticate before
+// running commands, which is the case unless the default user is enabled
+// and has nopass.
+func (a *ACL) RequiresAuth() bool {
+	u := a.User(DefaultUser)
+	return !u.Enabled || !u.NoPass
+}
@@ -0,0 +1,16 @@
+package acl
+
+import "slices"
+
+// Categories are the command categories rules can refer to as @name, in the
+// order ACL CAT lists them.
+var Categories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string",
+	"bitmap", "hyperloglog", "geo", "stream", "pubsub", "admin", "fast", "slow",
+	"blocking", "dangerous", "connection", "transaction", "scripting",
+}
+
+// IsCategory reports whether name is a category, "all" included.
+func IsCategory(name string) bool {
+	return name == "all" || slices.Contains(Categories, name)
+}
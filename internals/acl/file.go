@@ -0,0 +1,82 @@
+package acl
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadFile replaces every user with those defined in the ACL file at path,
+// one "user <name> <rules...>" line each. If any line is invalid, nothing
+// changes.
+func (a *ACL) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("ERR Error loading ACLs, opening file '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	users := map[string]*User{}
+	scan := bufio.NewScanner(f)
+	for n := 1; scan.Scan(); n++ {
+		fields := strings.Fields(scan.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return loadError(path, n, "should start with user keyword followed by the username")
+		}
+
+		name := fields[1]
+		if _, dup := users[name]; dup {
+			return loadError(path, n, fmt.Sprintf("duplicate user '%s' found", name))
+		}
+		u := NewUser(name)
+		for _, rule := range fields[2:] {
+			if err := u.apply(rule); err != nil {
+				return loadError(path, n, fmt.Sprintf("Error in applying operation '%s': %s", rule, err))
+			}
+		}
+		users[name] = u
+	}
+	if err := scan.Err(); err != nil {
+		return fmt.Errorf("ERR Error loading ACLs, reading file '%s': %w", path, err)
+	}
+
+	a.replace(users)
+	return nil
+}
+
+func loadError(path string, line int, msg string) error {
+	return fmt.Errorf("ERR %s:%d: %s. WARNING: ACL errors detected, no change to the previously active ACL rules was performed", path, line, msg)
+}
+
+// SaveFile writes every user to the ACL file at path. The file is replaced
+// atomically, so a failed save leaves the previous one intact.
+func (a *ACL) SaveFile(path string) error {
+	var b strings.Builder
+	for _, u := range a.Users() {
+		fmt.Fprintf(&b, "user %s %s\n", u.Name, u.Rules())
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,93 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxFailures = 5
+	DefaultBaseDelay   = time.Second
+	DefaultMaxDelay    = time.Minute
+
+	// forgetAfter is how long a host's failures are remembered once it
+	// stops trying.
+	forgetAfter = 10 * time.Minute
+	// pruneAbove is the number of tracked hosts above which forgotten ones
+	// are dropped.
+	pruneAbove = 1024
+)
+
+// Limiter slows down password guessing. Once a host has failed MaxFailures
+// times in a row, its attempts are refused without checking the password
+// for a delay that doubles with each further failure, up to MaxDelay. A
+// successful login clears the host's record.
+type Limiter struct {
+	MaxFailures int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*failures
+}
+
+type failures struct {
+	count int
+	last  time.Time
+	until time.Time // attempts are refused until then
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		MaxFailures: DefaultMaxFailures,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		hosts:       map[string]*failures{},
+	}
+}
+
+// Allow reports whether host may try a password now.
+func (l *Limiter) Allow(host string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.hosts[host]
+	return !ok || !time.Now().Before(f.until)
+}
+
+// Fail records a wrong password from host.
+func (l *Limiter) Fail(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	f, ok := l.hosts[host]
+	if !ok || now.Sub(f.last) > forgetAfter {
+		f = &failures{}
+		l.hosts[host] = f
+	}
+	f.count++
+	f.last = now
+	if over := f.count - l.MaxFailures; over >= 0 {
+		delay := l.MaxDelay
+		if over < 32 {
+			delay = min(l.BaseDelay<<over, l.MaxDelay)
+		}
+		f.until = now.Add(delay)
+	}
+
+	if len(l.hosts) > pruneAbove {
+		for h, f := range l.hosts {
+			if now.Sub(f.last) > forgetAfter {
+				delete(l.hosts, h)
+			}
+		}
+	}
+}
+
+// Succeed clears host's failures.
+func (l *Limiter) Succeed(host string) {
+	l.mu.Lock()
+	delete(l.hosts, host)
+	l.mu.Unlock()
+}
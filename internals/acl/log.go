@@ -0,0 +1,110 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLogMaxLen = 128
+
+	// groupWindow is how recent a matching entry must be for a new denial
+	// to be counted in it instead of getting its own entry.
+	groupWindow = time.Minute
+)
+
+// LogEntry records denied commands and failed logins for ACL LOG.
+type LogEntry struct {
+	Count      int
+	Reason     string // "command", "key", "channel" or "auth"
+	Context    string
+	Object     string
+	Username   string
+	ClientInfo string
+	EntryID    int64
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Log keeps the most recent entries, newest first.
+type Log struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+	maxLen  int
+	nextID  int64
+}
+
+func NewLog(maxLen int) *Log {
+	return &Log{maxLen: maxLen}
+}
+
+// Add records a denial. Repeats of a recent one only bump its count.
+func (l *Log) Add(reason, object, username, clientInfo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for i, e := range l.entries {
+		if e.Reason == reason && e.Object == object && e.Username == username &&
+			now.Sub(e.Updated) < groupWindow {
+			e.Count++
+			e.Updated = now
+			e.ClientInfo = clientInfo
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+
+	e := &LogEntry{
+		Count:      1,
+		Reason:     reason,
+		Context:    "toplevel",
+		Object:     object,
+		Username:   username,
+		ClientInfo: clientInfo,
+		EntryID:    l.nextID,
+		Created:    now,
+		Updated:    now,
+	}
+	l.nextID++
+	l.entries = append([]*LogEntry{e}, l.entries...)
+	l.trim()
+}
+
+// Entries returns copies of up to count entries, newest first; all of them
+// when count is negative.
+func (l *Log) Entries(count int) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+	out := make([]LogEntry, count)
+	for i := range out {
+		out[i] = *l.entries[i]
+	}
+	return out
+}
+
+func (l *Log) Reset() {
+	l.mu.Lock()
+	l.entries = nil
+	l.mu.Unlock()
+}
+
+// SetMaxLen changes how many entries are kept, dropping the oldest ones.
+func (l *Log) SetMaxLen(n int) {
+	l.mu.Lock()
+	l.maxLen = n
+	l.trim()
+	l.mu.Unlock()
+}
+
+func (l *Log) trim() {
+	if len(l.entries) > l.maxLen {
+		clear(l.entries[l.maxLen:])
+		l.entries = l.entries[:l.maxLen]
+	}
+}
@@ -0,0 +1,368 @@
+package acl
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/glob"
+)
+
+// User is an ACL user. Apart from Apply, which is only called on users not
+// yet visible to other goroutines, its methods do not modify it.
+type User struct {
+	Name    string
+	Enabled bool
+	NoPass  bool
+	// Passwords holds the SHA-256 hashes of the user's passwords, hex-encoded.
+	Passwords []string
+	Keys      []KeyPattern
+	Channels  []string
+
+	// commands holds the +/- rules in the order they were given; for a
+	// command, the last rule that matches it decides.
+	commands []commandRule
+}
+
+// KeyPattern lets a user read and/or write the keys matching Pattern.
+type KeyPattern struct {
+	Pattern     string
+	Read, Write bool
+}
+
+func (p KeyPattern) String() string {
+	switch {
+	case p.Read && p.Write:
+		return "~" + p.Pattern
+	case p.Read:
+		return "%R~" + p.Pattern
+	default:
+		return "%W~" + p.Pattern
+	}
+}
+
+type commandRule struct {
+	allow    bool
+	category string // set for +@category rules
+	command  string // "get" or "config|get" for +command rules
+}
+
+func (r commandRule) String() string {
+	sign := "-"
+	if r.allow {
+		sign = "+"
+	}
+	if r.category != "" {
+		return sign + "@" + r.category
+	}
+	return sign + r.command
+}
+
+func (r commandRule) matches(cmd, sub string, categories []string) bool {
+	switch {
+	case r.category == "all":
+		return true
+	case r.category != "":
+		return slices.Contains(categories, r.category)
+	default:
+		return r.command == cmd || (sub != "" && r.command == cmd+"|"+sub)
+	}
+}
+
+// NewUser returns a user that is off and may do nothing.
+func NewUser(name string) *User {
+	return &User{Name: name}
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.Passwords = slices.Clone(u.Passwords)
+	c.Keys = slices.Clone(u.Keys)
+	c.Channels = slices.Clone(u.Channels)
+	c.commands = slices.Clone(u.commands)
+	return &c
+}
+
+// Apply applies ACL SETUSER rules in order. On error the user may be left
+// half modified, so callers apply rules to a copy.
+func (u *User) Apply(rules ...string) error {
+	for _, rule := range rules {
+		if err := u.apply(rule); err != nil {
+			return fmt.Errorf("ERR Error in ACL SETUSER modifier '%s': %w", rule, err)
+		}
+	}
+	return nil
+}
+
+var (
+	errSyntax      = errors.New("Syntax error")
+	errUnknownName = errors.New("Unknown command or category name in ACL")
+	errNoPassword  = errors.New("The password you are trying to remove from the user does not exist")
+	errBadHash     = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+)
+
+func (u *User) apply(rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		u.Enabled = true
+		return nil
+	case "off":
+		u.Enabled = false
+		return nil
+	case "nopass":
+		u.NoPass, u.Passwords = true, nil
+		return nil
+	case "resetpass":
+		u.NoPass, u.Passwords = false, nil
+		return nil
+	case "allkeys":
+		return u.apply("~*")
+	case "resetkeys":
+		u.Keys = nil
+		return nil
+	case "allchannels":
+		return u.apply("&*")
+	case "resetchannels":
+		u.Channels = nil
+		return nil
+	case "allcommands":
+		return u.apply("+@all")
+	case "nocommands":
+		return u.apply("-@all")
+	case "reset":
+		for _, r := range []string{"resetpass", "resetkeys", "resetchannels", "off", "-@all"} {
+			_ = u.apply(r)
+		}
+		return nil
+	}
+
+	if rule == "" {
+		return errSyntax
+	}
+	switch op, arg := rule[0], rule[1:]; op {
+	case '>':
+		u.addPassword(HashPassword(arg))
+	case '<':
+		return u.removePassword(HashPassword(arg))
+	case '#':
+		if !validHash(arg) {
+			return errBadHash
+		}
+		u.addPassword(arg)
+	case '!':
+		if !validHash(arg) {
+			return errBadHash
+		}
+		return u.removePassword(arg)
+	case '~':
+		u.addKeyPattern(KeyPattern{Pattern: arg, Read: true, Write: true})
+	case '%':
+		flags, pattern, ok := strings.Cut(arg, "~")
+		if !ok || flags == "" {
+			return errSyntax
+		}
+		p := KeyPattern{Pattern: pattern}
+		for _, f := range strings.ToUpper(flags) {
+			switch f {
+			case 'R':
+				p.Read = true
+			case 'W':
+				p.Write = true
+			default:
+				return errSyntax
+			}
+		}
+		u.addKeyPattern(p)
+	case '&':
+		if !slices.Contains(u.Channels, arg) {
+			u.Channels = append(u.Channels, arg)
+		}
+	case '+', '-':
+		return u.addCommandRule(op == '+', arg)
+	default:
+		return errSyntax
+	}
+	return nil
+}
+
+func validHash(h string) bool {
+	if len(h) != 64 || strings.ToLower(h) != h {
+		return false
+	}
+	_, err := hex.DecodeString(h)
+	return err == nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.NoPass = false
+	if !slices.Contains(u.Passwords, hash) {
+		u.Passwords = append(u.Passwords, hash)
+	}
+}
+
+func (u *User) removePassword(hash string) error {
+	i := slices.Index(u.Passwords, hash)
+	if i < 0 {
+		return errNoPassword
+	}
+	u.Passwords = slices.Delete(u.Passwords, i, i+1)
+	return nil
+}
+
+// addKeyPattern adds p, merging its permissions into an existing entry for
+// the same pattern.
+func (u *User) addKeyPattern(p KeyPattern) {
+	for i, cur := range u.Keys {
+		if cur.Pattern == p.Pattern {
+			u.Keys[i].Read = cur.Read || p.Read
+			u.Keys[i].Write = cur.Write || p.Write
+			return
+		}
+	}
+	u.Keys = append(u.Keys, p)
+}
+
+func (u *User) addCommandRule(allow bool, name string) error {
+	r := commandRule{allow: allow}
+	if cat, ok := strings.CutPrefix(name, "@"); ok {
+		if !IsCategory(strings.ToLower(cat)) {
+			return errUnknownName
+		}
+		r.category = strings.ToLower(cat)
+	} else {
+		if !KnownCommand(strings.ToLower(name)) {
+			return errUnknownName
+		}
+		r.command = strings.ToLower(name)
+	}
+
+	// +@all and -@all override everything before them.
+	if r.category == "all" {
+		u.commands = u.commands[:0]
+		if allow {
+			u.commands = append(u.commands, r)
+		}
+		return nil
+	}
+	u.commands = slices.DeleteFunc(u.commands, func(cur commandRule) bool {
+		return cur.category == r.category && cur.command == r.command
+	})
+	u.commands = append(u.commands, r)
+	return nil
+}
+
+// CanRun reports whether the user may run cmd, or its subcommand sub when
+// sub is set. categories are those of the command or subcommand.
+func (u *User) CanRun(cmd, sub string, categories []string) bool {
+	allowed := false
+	for _, r := range u.commands {
+		if r.matches(cmd, sub, categories) {
+			allowed = r.allow
+		}
+	}
+	return allowed
+}
+
+// CanAccessKey reports whether a single pattern of the user grants the
+// requested access to key.
+func (u *User) CanAccessKey(key string, read, write bool) bool {
+	for _, p := range u.Keys {
+		if (read && !p.Read) || (write && !p.Write) {
+			continue
+		}
+		if glob.Match(p.Pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) CanAccessChannel(channel string) bool {
+	for _, p := range u.Channels {
+		if glob.Match(p, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *User) checkPassword(pass string) bool {
+	if u.NoPass {
+		return true
+	}
+	given := []byte(HashPassword(pass))
+	ok := false
+	for _, h := range u.Passwords {
+		// Keep comparing after a match so the time taken does not tell
+		// which password matched.
+		if subtle.ConstantTimeCompare(given, []byte(h)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// CommandRules describes the user's command permissions, e.g.
+// "-@all +@read -keys".
+func (u *User) CommandRules() string {
+	parts := make([]string, 0, len(u.commands)+1)
+	if len(u.commands) == 0 || u.commands[0].category != "all" {
+		parts = append(parts, "-@all")
+	}
+	for _, r := range u.commands {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// KeyRules describes the key patterns, e.g. "~cache:* %R~config:*".
+func (u *User) KeyRules() string {
+	parts := make([]string, 0, len(u.Keys))
+	for _, p := range u.Keys {
+		parts = append(parts, p.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// ChannelRules describes the channel patterns, e.g. "&news.*".
+func (u *User) ChannelRules() string {
+	parts := make([]string, 0, len(u.Channels))
+	for _, p := range u.Channels {
+		parts = append(parts, "&"+p)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Flags returns the user's flags as ACL GETUSER reports them.
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.Enabled {
+		flags[0] = "on"
+	}
+	if u.NoPass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// Rules describes the user as a list of rules that recreate it, as in
+// ACL LIST and the ACL file.
+func (u *User) Rules() string {
+	parts := u.Flags()
+	for _, h := range u.Passwords {
+		parts = append(parts, "#"+h)
+	}
+	if k := u.KeyRules(); k != "" {
+		parts = append(parts, k)
+	}
+	if ch := u.ChannelRules(); ch != "" {
+		parts = append(parts, ch)
+	} else {
+		parts = append(parts, "resetchannels")
+	}
+	parts = append(parts, u.CommandRules())
+	return strings.Join(parts, " ")
+}
@@ -1,22 +1,277 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 	myio "github.com/shivakuppa/Go_Redis/internals/io"
 )
 
+// nextID hands out client IDs; like Redis they are never reused.
+var nextID atomic.Int64
+
+// pushQueueLimit bounds the pushes queued for a client that does not read
+// them. A client that falls this far behind is disconnected, like Redis does
+// when a client exceeds its pub/sub output buffer limit.
+const pushQueueLimit = 16 * 1024
+
+// ErrPushQueueFull is returned by Push when the client was disconnected
+// for falling too far behind.
+var ErrPushQueueFull = errors.New("push queue full")
+
 type Client struct {
+	ID            int64
 	Conn          net.Conn
 	Authenticated bool
+	Protocol      int // 2 or 3; change it with SetProtocol
+	Reader        *resp.Reader
+	Writer        *myio.RespWriter
+
+	// CloseAfterReply asks the server to close the connection once the
+	// reply to the current command is written, as after QUIT.
+	CloseAfterReply bool
+
+	closeFn func() error
+	created time.Time
+
+	// mu guards what other clients' goroutines read or change: the fields
+	// below, Protocol and Authenticated. It is never held during I/O, so a
+	// stalled connection cannot block them.
+	mu     sync.Mutex
+	user   string // the ACL user commands run as
+	name   string
+	pushes []*resp.Value
+	// notify tells the connection's writer that pushes are queued.
+	notify    func()
+	pushReady chan struct{}
+
+	// wmu serialises use of Writer between the connection's goroutine and
+	// whatever delivers its pushes.
+	wmu sync.Mutex
 }
 
 func NewClient(conn net.Conn) *Client {
-	return &Client{Conn: conn}
+	return &Client{
+		ID:       nextID.Add(1),
+		user:     "default",
+		created:  time.Now(),
+		Conn:     conn,
+		Protocol: 2,
+		Reader:   resp.NewReader(conn),
+		Writer:   myio.NewRespWriter(conn),
+
+		pushReady: make(chan struct{}, 1),
+	}
+}
+
+// User returns the name of the ACL user the client is authenticated as.
+func (c *Client) User() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.user
+}
+
+// SetUser marks the client as authenticated as the named ACL user.
+func (c *Client) SetUser(name string) {
+	c.mu.Lock()
+	c.user = name
+	c.Authenticated = true
+	c.mu.Unlock()
+}
+
+// Name returns the name set with CLIENT SETNAME or HELLO SETNAME.
+func (c *Client) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.name
+}
+
+func (c *Client) SetName(name string) {
+	c.mu.Lock()
+	c.name = name
+	c.mu.Unlock()
+}
+
+// SetCloser replaces how Close closes the connection, for servers that must
+// close it themselves. It must be called before the client is shared.
+func (c *Client) SetCloser(fn func() error) {
+	c.closeFn = fn
+}
+
+// Close disconnects the client, e.g. when its ACL user is deleted.
+func (c *Client) Close() error {
+	if c.closeFn != nil {
+		return c.closeFn()
+	}
+	return c.Conn.Close()
+}
+
+// Info describes the client in CLIENT LIST format.
+func (c *Client) Info() string {
+	addr, laddr := c.addrs()
+	flags := "N"
+	if c.Unix() {
+		flags = "U"
+	}
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d flags=%s user=%s resp=%d",
+		c.ID, addr, laddr, c.Name(), int64(time.Since(c.created).Seconds()), flags, c.User(), c.protocol())
+}
+
+// Unix reports whether the client is connected through a unix socket.
+func (c *Client) Unix() bool {
+	_, ok := c.Conn.LocalAddr().(*net.UnixAddr)
+	return ok
+}
+
+// Local reports whether the client connects from the same host, through
+// the loopback interface or a unix socket.
+func (c *Client) Local() bool {
+	if c.Unix() {
+		return true
+	}
+	addr, ok := c.Conn.RemoteAddr().(*net.TCPAddr)
+	return ok && addr.IP.IsLoopback()
+}
+
+// addrs returns the remote and local addresses of the connection. As in
+// Redis, both are "<socket path>:0" for unix socket clients, whose peers
+// have no address of their own.
+func (c *Client) addrs() (addr, laddr string) {
+	if c.Unix() {
+		path := c.Conn.LocalAddr().String() + ":0"
+		return path, path
+	}
+	return c.Conn.RemoteAddr().String(), c.Conn.LocalAddr().String()
+}
+
+func (c *Client) protocol() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Protocol
+}
+
+// Host returns the address the client connects from, without the port.
+// All unix socket clients share the socket path.
+func (c *Client) Host() string {
+	addr, _ := c.addrs()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// Write buffers a reply, downgrading RESP3 types unless the client has
+// negotiated RESP3. Pushes queued until then go out first.
+func (c *Client) Write(v *resp.Value) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.writePushes(); err != nil {
+		return err
+	}
+	return c.write(v)
+}
+
+func (c *Client) write(v *resp.Value) error {
+	if !c.RESP3() {
+		v = resp.ToRESP2(v)
+	}
+	return c.Writer.Write(v)
+}
+
+// SetProtocol switches the protocol used for subsequent replies.
+func (c *Client) SetProtocol(proto int) {
+	c.mu.Lock()
+	c.Protocol = proto
+	c.mu.Unlock()
+}
+
+// RESP3 reports whether the client negotiated RESP3. Unlike reading
+// Protocol, it is safe to call from other clients' goroutines.
+func (c *Client) RESP3() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Protocol >= 3
+}
+
+func (c *Client) Flush() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.Writer.Flush()
+}
+
+// Push queues an out-of-band message, such as a pub/sub message or a
+// tracking invalidation, for the client's own writer to send. It never
+// blocks on the client's connection, so a slow reader cannot hold up the
+// command that pushes to it; one that lets pushQueueLimit messages pile up
+// is disconnected instead.
+func (c *Client) Push(v *resp.Value) error {
+	c.mu.Lock()
+	if len(c.pushes) >= pushQueueLimit {
+		c.mu.Unlock()
+		_ = c.Close()
+		return ErrPushQueueFull
+	}
+	c.pushes = append(c.pushes, v)
+	first := len(c.pushes) == 1
+	notify := c.notify
+	c.mu.Unlock()
+
+	if !first {
+		return nil // the writer has been told already
+	}
+	if notify != nil {
+		notify()
+	} else {
+		select {
+		case c.pushReady <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// PushReady is signalled when pushes are queued, unless SetPushNotifier
+// replaced that.
+func (c *Client) PushReady() <-chan struct{} {
+	return c.pushReady
+}
+
+// SetPushNotifier replaces how the connection's writer is told that pushes
+// are queued. fn may be called from any goroutine. It must be set before
+// the client is shared.
+func (c *Client) SetPushNotifier(fn func()) {
+	c.notify = fn
+}
+
+// FlushPushes writes out the queued pushes.
+func (c *Client) FlushPushes() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.writePushes(); err != nil {
+		return err
+	}
+	return c.Writer.Flush()
+}
+
+// writePushes buffers the queued pushes. wmu must be held.
+func (c *Client) writePushes() error {
+	c.mu.Lock()
+	pushes := c.pushes
+	c.pushes = nil
+	c.mu.Unlock()
+
+	for _, v := range pushes {
+		if err := c.write(v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *Client) writeMonitorLog(value *resp.Value) {
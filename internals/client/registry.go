@@ -0,0 +1,50 @@
+package client
+
+import "sync"
+
+// Registry indexes the connected clients by ID.
+type Registry struct {
+	mu      sync.RWMutex
+	clients map[int64]*Client
+}
+
+func NewRegistry() *Registry {
+	return &Registry{clients: map[int64]*Client{}}
+}
+
+func (r *Registry) Add(c *Client) {
+	r.mu.Lock()
+	r.clients[c.ID] = c
+	r.mu.Unlock()
+}
+
+func (r *Registry) Remove(c *Client) {
+	r.mu.Lock()
+	delete(r.clients, c.ID)
+	r.mu.Unlock()
+}
+
+// Get returns the connected client with the given ID, or nil.
+func (r *Registry) Get(id int64) *Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clients[id]
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.clients)
+}
+
+// All returns the connected clients.
+func (r *Registry) All() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*Client, 0, len(r.clients))
+	for _, c := range r.clients {
+		all = append(all, c)
+	}
+	return all
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+func aclCmd(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	sub := strings.ToUpper(value.Array[1].String)
+	args := value.Array[2:]
+	switch {
+	case sub == "SETUSER":
+		rules := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			rules = append(rules, arg.String)
+		}
+		if err := state.ACL.SetUser(args[0].String, rules...); err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+		}
+		return resp.OK
+
+	case sub == "GETUSER":
+		return aclGetUser(state.ACL.User(args[0].String))
+
+	case sub == "DELUSER":
+		names := make([]string, 0, len(args))
+		for _, arg := range args {
+			names = append(names, arg.String)
+		}
+		n, err := state.ACL.DelUser(names...)
+		if err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+		}
+		disconnectOrphans(state)
+		return &resp.Value{Type: resp.Integer, Integer: int64(n)}
+
+	case sub == "LIST":
+		users := state.ACL.Users()
+		lines := make([]string, 0, len(users))
+		for _, u := range users {
+			lines = append(lines, "user "+u.Name+" "+u.Rules())
+		}
+		return stringArray(lines)
+
+	case sub == "USERS":
+		users := state.ACL.Users()
+		names := make([]string, 0, len(users))
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		return stringArray(names)
+
+	case sub == "WHOAMI":
+		return &resp.Value{Type: resp.BulkString, String: c.User()}
+
+	case sub == "CAT" && len(args) <= 1:
+		if len(args) == 0 {
+			return stringArray(acl.Categories)
+		}
+		category := strings.ToLower(args[0].String)
+		if !acl.IsCategory(category) || category == "all" {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR Unknown category '" + args[0].String + "'"}
+		}
+		names := commandsInCategory(category)
+		slices.Sort(names)
+		return stringArray(names)
+
+	case sub == "LOG" && len(args) <= 1:
+		return aclLog(args, state)
+
+	case sub == "DRYRUN":
+		user := state.ACL.User(args[0].String)
+		if user == nil {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR User '" + args[0].String + "' not found"}
+		}
+		v := &resp.Value{Type: resp.Array, Array: args[1:]}
+		if _, ok := commandTable[strings.ToUpper(args[1].String)]; !ok {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR Command '" + args[1].String + "' not found"}
+		}
+		if reason, object := aclDenial(user, v); reason != "" {
+			return &resp.Value{Type: resp.BulkString, String: denialMessage(user.Name, reason, object)}
+		}
+		return resp.OK
+
+	case sub == "LOAD":
+		if state.Config.ACLFile == "" {
+			return errNoACLFile
+		}
+		if err := state.ACL.LoadFile(state.Config.ACLFile); err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+		}
+		disconnectOrphans(state)
+		return resp.OK
+
+	case sub == "SAVE":
+		if state.Config.ACLFile == "" {
+			return errNoACLFile
+		}
+		if err := state.ACL.SaveFile(state.Config.ACLFile); err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR There was an error trying to save the ACLs. Please check the server logs for more information"}
+		}
+		return resp.OK
+
+	default:
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR unknown subcommand or wrong number of arguments for '" + value.Array[1].String + "'. Try ACL HELP.",
+		}
+	}
+}
+
+var errNoACLFile = &resp.Value{
+	Type:   resp.SimpleError,
+	String: "ERR This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.",
+}
+
+func aclGetUser(u *acl.User) *resp.Value {
+	if u == nil {
+		return &resp.Value{Type: resp.Null, IsNull: true}
+	}
+
+	reply := &resp.Value{Type: resp.Map}
+	reply.AddPair("flags", stringArray(u.Flags()))
+	reply.AddPair("passwords", stringArray(u.Passwords))
+	reply.AddPair("commands", &resp.Value{Type: resp.BulkString, String: u.CommandRules()})
+	reply.AddPair("keys", &resp.Value{Type: resp.BulkString, String: u.KeyRules()})
+	reply.AddPair("channels", &resp.Value{Type: resp.BulkString, String: u.ChannelRules()})
+	reply.AddPair("selectors", &resp.Value{Type: resp.Array, Array: []*resp.Value{}})
+	return reply
+}
+
+// aclLog implements ACL LOG [count | RESET].
+func aclLog(args []*resp.Value, state *db.AppState) *resp.Value {
+	count := 10
+	if len(args) == 1 {
+		if strings.EqualFold(args[0].String, "RESET") {
+			state.ACL.Log.Reset()
+			return resp.OK
+		}
+		n, err := strconv.Atoi(args[0].String)
+		if err != nil || n < 0 {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR value is out of range, must be positive"}
+		}
+		count = n
+	}
+
+	now := time.Now()
+	entries := state.ACL.Log.Entries(count)
+	reply := &resp.Value{Type: resp.Array, Array: make([]*resp.Value, 0, len(entries))}
+	for _, e := range entries {
+		entry := &resp.Value{Type: resp.Map}
+		entry.AddPair("count", &resp.Value{Type: resp.Integer, Integer: int64(e.Count)})
+		entry.AddPair("reason", &resp.Value{Type: resp.BulkString, String: e.Reason})
+		entry.AddPair("context", &resp.Value{Type: resp.BulkString, String: e.Context})
+		entry.AddPair("object", &resp.Value{Type: resp.BulkString, String: e.Object})
+		entry.AddPair("username", &resp.Value{Type: resp.BulkString, String: e.Username})
+		entry.AddPair("age-seconds", &resp.Value{Type: resp.Double, Double: now.Sub(e.Created).Seconds()})
+		entry.AddPair("client-info", &resp.Value{Type: resp.BulkString, String: e.ClientInfo})
+		entry.AddPair("entry-id", &resp.Value{Type: resp.Integer, Integer: e.EntryID})
+		entry.AddPair("timestamp-created", &resp.Value{Type: resp.Integer, Integer: e.Created.UnixMilli()})
+		entry.AddPair("timestamp-last-updated", &resp.Value{Type: resp.Integer, Integer: e.Updated.UnixMilli()})
+		reply.Array = append(reply.Array, entry)
+	}
+	return reply
+}
+
+// disconnectOrphans disconnects the clients whose user no longer exists.
+func disconnectOrphans(state *db.AppState) {
+	for _, c := range state.Clients.All() {
+		if state.ACL.User(c.User()) == nil {
+			_ = c.Close()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/tracking"
+)
+
+func clientCmd(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	sub := strings.ToUpper(value.Array[1].String)
+	args := value.Array[2:]
+	switch sub {
+	case "ID":
+		return &resp.Value{Type: resp.Integer, Integer: c.ID}
+
+	case "GETNAME":
+		name := c.Name()
+		if name == "" {
+			return resp.NullBulk
+		}
+		return &resp.Value{Type: resp.BulkString, String: name}
+
+	case "SETNAME":
+		if strings.ContainsAny(args[0].String, " \n") {
+			return &resp.Value{
+				Type:   resp.SimpleError,
+				String: "ERR Client names cannot contain spaces, newlines or special characters.",
+			}
+		}
+		c.SetName(args[0].String)
+		return resp.OK
+
+	case "INFO":
+		return &resp.Value{Type: resp.BulkString, String: c.Info() + "\n"}
+
+	case "LIST":
+		return clientList(args, state)
+
+	case "TRACKING":
+		return clientTracking(c, args, state)
+
+	case "CACHING":
+		var yes bool
+		switch strings.ToLower(args[0].String) {
+		case "yes":
+			yes = true
+		case "no":
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+		if err := state.Tracking.SetCaching(c, yes); err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+		}
+		return resp.OK
+
+	case "GETREDIR":
+		opts, on := state.Tracking.Options(c)
+		if !on {
+			return &resp.Value{Type: resp.Integer, Integer: -1}
+		}
+		return &resp.Value{Type: resp.Integer, Integer: opts.Redirect}
+
+	case "TRACKINGINFO":
+		flags, redirect, prefixes := state.Tracking.Info(c)
+		reply := &resp.Value{Type: resp.Map}
+		reply.AddPair("flags", stringSet(flags))
+		reply.AddPair("redirect", &resp.Value{Type: resp.Integer, Integer: redirect})
+		reply.AddPair("prefixes", stringArray(prefixes))
+		return reply
+
+	default:
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR unknown subcommand or wrong number of arguments for '" + value.Array[1].String + "'. Try CLIENT HELP.",
+		}
+	}
+}
+
+// clientList implements CLIENT LIST [ID id [id ...]].
+func clientList(args []*resp.Value, state *db.AppState) *resp.Value {
+	var ids map[int64]bool
+	if len(args) > 0 {
+		if !strings.EqualFold(args[0].String, "ID") || len(args) < 2 {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+		ids = map[int64]bool{}
+		for _, arg := range args[1:] {
+			id, err := strconv.ParseInt(arg.String, 10, 64)
+			if err != nil || id < 1 {
+				return &resp.Value{Type: resp.SimpleError, String: "ERR Invalid client ID"}
+			}
+			ids[id] = true
+		}
+	}
+
+	clients := state.Clients.All()
+	slices.SortFunc(clients, func(a, b *client.Client) int { return cmp.Compare(a.ID, b.ID) })
+
+	var b strings.Builder
+	for _, cl := range clients {
+		if ids == nil || ids[cl.ID] {
+			b.WriteString(cl.Info())
+			b.WriteByte('\n')
+		}
+	}
+	return &resp.Value{Type: resp.BulkString, String: b.String()}
+}
+
+// clientTracking implements CLIENT TRACKING ON|OFF [REDIRECT id]
+// [PREFIX prefix ...] [BCAST] [OPTIN] [OPTOUT] [NOLOOP].
+func clientTracking(c *client.Client, args []*resp.Value, state *db.AppState) *resp.Value {
+	var on bool
+	switch strings.ToLower(args[0].String) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+	}
+
+	var opts tracking.Options
+	for i := 1; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i].String); {
+		case opt == "BCAST":
+			opts.BCast = true
+		case opt == "OPTIN":
+			opts.OptIn = true
+		case opt == "OPTOUT":
+			opts.OptOut = true
+		case opt == "NOLOOP":
+			opts.NoLoop = true
+		case opt == "REDIRECT" && i+1 < len(args):
+			if opts.Redirect != 0 {
+				return &resp.Value{Type: resp.SimpleError, String: "ERR A client can only redirect to a single other client"}
+			}
+			id, err := strconv.ParseInt(args[i+1].String, 10, 64)
+			if err != nil {
+				return &resp.Value{Type: resp.SimpleError, String: "ERR value is not an integer or out of range"}
+			}
+			opts.Redirect = id
+			i++
+		case opt == "PREFIX" && i+1 < len(args):
+			opts.Prefixes = append(opts.Prefixes, args[i+1].String)
+			i++
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+	}
+
+	if !on {
+		state.Tracking.Disable(c)
+		return resp.OK
+	}
+	if err := state.Tracking.Enable(c, opts); err != nil {
+		return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+	}
+	return resp.OK
+}
+
+func stringArray(ss []string) *resp.Value {
+	arr := &resp.Value{Type: resp.Array, Array: make([]*resp.Value, 0, len(ss))}
+	for _, s := range ss {
+		arr.Array = append(arr.Array, &resp.Value{Type: resp.BulkString, String: s})
+	}
+	return arr
+}
+
+func stringSet(ss []string) *resp.Value {
+	set := &resp.Value{Type: resp.Set, Set: make([]*resp.Value, 0, len(ss))}
+	for _, s := range ss {
+		set.Set = append(set.Set, &resp.Value{Type: resp.BulkString, String: s})
+	}
+	return set
+}
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// command implements COMMAND and its subcommands, which describe the
+// commands in commandTable.
+func command(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+	if len(args) == 0 {
+		return &resp.Value{Type: resp.Array, Array: commandInfos(commandNames())}
+	}
+
+	sub := strings.ToUpper(args[0].String)
+	args = args[1:]
+	switch {
+	case sub == "COUNT":
+		return &resp.Value{Type: resp.Integer, Integer: int64(len(commandTable))}
+
+	case sub == "INFO":
+		names := commandNames()
+		if len(args) > 0 {
+			names = argStrings(args)
+		}
+		return &resp.Value{Type: resp.Array, Array: commandInfos(names)}
+
+	case sub == "DOCS":
+		names := commandNames()
+		if len(args) > 0 {
+			names = argStrings(args)
+		}
+		reply := &resp.Value{Type: resp.Map, Map: []resp.MapEntry{}}
+		for _, name := range names {
+			if meta := findCommand(name); meta != nil {
+				reply.AddPair(strings.ToLower(name), commandDocs(strings.ToLower(name), meta))
+			}
+		}
+		return reply
+
+	case sub == "LIST":
+		return commandList(args)
+
+	case sub == "GETKEYS":
+		return commandGetKeys(args)
+
+	default:
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR unknown subcommand or wrong number of arguments for '" + value.Array[1].String + "'. Try COMMAND HELP.",
+		}
+	}
+}
+
+// commandNames returns the lowercase names of the commands, sorted.
+func commandNames() []string {
+	names := make([]string, 0, len(commandTable))
+	for name := range commandTable {
+		names = append(names, strings.ToLower(name))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// findCommand returns the metadata of the command name, which may be a
+// subcommand in "cmd|sub" form, or nil if there is no such command.
+func findCommand(name string) *CommandMeta {
+	cmd, sub, hasSub := strings.Cut(strings.ToLower(name), "|")
+	meta := commandTable[strings.ToUpper(cmd)]
+	if meta == nil || !hasSub {
+		return meta
+	}
+	return meta.Subcommands[sub]
+}
+
+func argStrings(args []*resp.Value) []string {
+	ss := make([]string, len(args))
+	for i, arg := range args {
+		ss[i] = arg.String
+	}
+	return ss
+}
+
+// commandInfos describes each of names as COMMAND INFO does, with a null
+// for names that are not commands.
+func commandInfos(names []string) []*resp.Value {
+	infos := make([]*resp.Value, 0, len(names))
+	for _, name := range names {
+		meta := findCommand(name)
+		if meta == nil {
+			infos = append(infos, &resp.Value{Type: resp.Array, IsNull: true})
+			continue
+		}
+		infos = append(infos, commandInfo(strings.ToLower(name), meta))
+	}
+	return infos
+}
+
+// commandInfo is the COMMAND INFO entry for meta: name, arity, flags,
+// first key, last key, key step, ACL categories, tips, key specs and
+// subcommands.
+func commandInfo(name string, meta *CommandMeta) *resp.Value {
+	first, last, step := meta.legacyKeyRange()
+
+	categories := make([]string, len(meta.Categories))
+	for i, cat := range meta.Categories {
+		categories[i] = "@" + cat
+	}
+
+	specs := make([]*resp.Value, 0, len(meta.Keys))
+	for _, spec := range meta.Keys {
+		specs = append(specs, keySpecInfo(spec))
+	}
+
+	subs := make([]*resp.Value, 0, len(meta.Subcommands))
+	for _, sub := range slices.Sorted(maps.Keys(meta.Subcommands)) {
+		subs = append(subs, commandInfo(name+"|"+sub, meta.Subcommands[sub]))
+	}
+
+	return &resp.Value{Type: resp.Array, Array: []*resp.Value{
+		{Type: resp.BulkString, String: name},
+		{Type: resp.Integer, Integer: int64(meta.Arity)},
+		statusSet(meta.Flags),
+		{Type: resp.Integer, Integer: int64(first)},
+		{Type: resp.Integer, Integer: int64(last)},
+		{Type: resp.Integer, Integer: int64(step)},
+		statusSet(categories),
+		{Type: resp.Array, Array: []*resp.Value{}},
+		{Type: resp.Array, Array: specs},
+		{Type: resp.Array, Array: subs},
+	}}
+}
+
+// legacyKeyRange returns the first key, last key and step that span every
+// key spec of meta, as in the COMMAND reply before key specs existed.
+func (meta *CommandMeta) legacyKeyRange() (first, last, step int) {
+	if len(meta.Keys) == 0 {
+		return 0, 0, 0
+	}
+	first, last, step = meta.Keys[0].First, meta.Keys[0].Last, max(meta.Keys[0].Step, 1)
+	for _, spec := range meta.Keys[1:] {
+		first = min(first, spec.First)
+		if last >= 0 && (spec.Last < 0 || spec.Last > last) {
+			last = spec.Last
+		}
+		step = 1
+	}
+	return first, last, step
+}
+
+// keySpecInfo describes spec in the key specs format of COMMAND INFO.
+func keySpecInfo(spec KeySpec) *resp.Value {
+	var flags []string
+	switch {
+	case spec.Read && spec.Write:
+		flags = []string{"RW", "ACCESS", "UPDATE"}
+	case spec.Write:
+		flags = []string{"OW", "UPDATE"}
+	default:
+		flags = []string{"RO", "ACCESS"}
+	}
+
+	// lastkey counts from the first key, or from the end if negative.
+	lastKey := spec.Last
+	if lastKey >= 0 {
+		lastKey -= spec.First
+	}
+
+	index := &resp.Value{Type: resp.Map}
+	index.AddPair("index", &resp.Value{Type: resp.Integer, Integer: int64(spec.First)})
+	begin := &resp.Value{Type: resp.Map}
+	begin.AddPair("type", &resp.Value{Type: resp.BulkString, String: "index"})
+	begin.AddPair("spec", index)
+
+	rng := &resp.Value{Type: resp.Map}
+	rng.AddPair("lastkey", &resp.Value{Type: resp.Integer, Integer: int64(lastKey)})
+	rng.AddPair("keystep", &resp.Value{Type: resp.Integer, Integer: int64(max(spec.Step, 1))})
+	rng.AddPair("limit", &resp.Value{Type: resp.Integer, Integer: 0})
+	find := &resp.Value{Type: resp.Map}
+	find.AddPair("type", &resp.Value{Type: resp.BulkString, String: "range"})
+	find.AddPair("spec", rng)
+
+	info := &resp.Value{Type: resp.Map}
+	info.AddPair("flags", statusSet(flags))
+	info.AddPair("begin_search", begin)
+	info.AddPair("find_keys", find)
+	return info
+}
+
+// commandDocs is the COMMAND DOCS entry for meta. Subcommands share their
+// container's group.
+func commandDocs(name string, meta *CommandMeta) *resp.Value {
+	docs := &resp.Value{Type: resp.Map}
+	docs.AddPair("summary", &resp.Value{Type: resp.BulkString, String: meta.Summary})
+	docs.AddPair("group", &resp.Value{Type: resp.BulkString, String: meta.Group})
+	if len(meta.Subcommands) > 0 {
+		subs := &resp.Value{Type: resp.Map}
+		for _, sub := range slices.Sorted(maps.Keys(meta.Subcommands)) {
+			subMeta := *meta.Subcommands[sub]
+			subMeta.Group = meta.Group
+			subs.AddPair(name+"|"+sub, commandDocs(name+"|"+sub, &subMeta))
+		}
+		docs.AddPair("subcommands", subs)
+	}
+	return docs
+}
+
+// commandList implements COMMAND LIST [FILTERBY MODULE name | ACLCAT
+// category | PATTERN pattern]. Subcommands are listed as "cmd|sub".
+func commandList(args []*resp.Value) *resp.Value {
+	match := func(string, *CommandMeta) bool { return true }
+	switch {
+	case len(args) == 0:
+	case len(args) == 3 && strings.EqualFold(args[0].String, "FILTERBY"):
+		filter, arg := strings.ToUpper(args[1].String), args[2].String
+		switch filter {
+		case "MODULE":
+			// There are no modules.
+			return &resp.Value{Type: resp.Array, Array: []*resp.Value{}}
+		case "ACLCAT":
+			match = func(_ string, meta *CommandMeta) bool {
+				return slices.Contains(meta.Categories, strings.ToLower(arg))
+			}
+		case "PATTERN":
+			match = func(name string, _ *CommandMeta) bool {
+				ok, _ := filepath.Match(strings.ToLower(arg), name)
+				return ok
+			}
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+	default:
+		return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+	}
+
+	var names []string
+	for _, name := range commandNames() {
+		meta := commandTable[strings.ToUpper(name)]
+		if match(name, meta) {
+			names = append(names, name)
+		}
+		for _, sub := range slices.Sorted(maps.Keys(meta.Subcommands)) {
+			if full := name + "|" + sub; match(full, meta.Subcommands[sub]) {
+				names = append(names, full)
+			}
+		}
+	}
+	return stringArray(names)
+}
+
+// commandGetKeys implements COMMAND GETKEYS command [arg ...], returning
+// the arguments of the command that are keys.
+func commandGetKeys(args []*resp.Value) *resp.Value {
+	v := &resp.Value{Type: resp.Array, Array: args}
+	meta, _, _ := lookupMeta(v)
+	if meta == nil {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR Invalid command specified"}
+	}
+	if (meta.Arity > 0 && len(args) != meta.Arity) || len(args) < -meta.Arity {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR Invalid number of arguments specified for command"}
+	}
+
+	var keys []string
+	for _, spec := range meta.Keys {
+		keys = append(keys, spec.args(v)...)
+	}
+	if len(keys) == 0 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR The command has no key arguments"}
+	}
+	return stringArray(keys)
+}
+
+// statusSet returns ss as a set of simple strings, as COMMAND INFO reports
+// flags and categories.
+func statusSet(ss []string) *resp.Value {
+	set := &resp.Value{Type: resp.Set, Set: make([]*resp.Value, 0, len(ss))}
+	for _, s := range ss {
+		set.Set = append(set.Set, &resp.Value{Type: resp.SimpleString, String: s})
+	}
+	return set
+}
@@ -2,57 +2,289 @@ package commands
 
 import (
 	"fmt"
-	"net"
 	"strings"
 
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
-type CmdHandler func(*resp.Value, *db.AppState) *resp.Value
+// CmdHandler executes one command for client c. The reply may use RESP3
+// types; it is downgraded on the way out for RESP2 clients.
+type CmdHandler func(c *client.Client, v *resp.Value, state *db.AppState) *resp.Value
 
-var CmdHandlers = map[string]CmdHandler{
-	// Connection Commands
-	CMD_COMMAND: 	command,
-
-	// Key Commands
-	CMD_DEL: 		del,
-	CMD_EXISTS:		exists,
-	CMD_KEYS:		keys,
-
-	// String Commands
-	CMD_SET:    	set,
-	CMD_GET:     	get,
+// loadingAllowed lists the commands served while the dataset is loading.
+var loadingAllowed = map[string]bool{
+	CMD_INFO:     true,
+	CMD_COMMAND:  true,
+	CMD_HELLO:    true,
+	CMD_AUTH:     true,
+	CMD_QUIT:     true,
+	CMD_LASTSAVE: true,
+	CMD_SHUTDOWN: true,
+}
 
-	// Extra Commands
-	"SAVE":			save,
-	"BGSAVE":		bgsave,
-	"FLUSHDB":		flushdb,
-	"DBSIZE":		dbsize,
-	"EXPIRE":		expire,
-	"TTL":			ttl,
+// noAuthAllowed lists the commands a client may send before authenticating.
+var noAuthAllowed = map[string]bool{
+	CMD_AUTH:  true,
+	CMD_HELLO: true,
+	CMD_QUIT:  true,
 }
 
-func HandleCommand(conn net.Conn, value *resp.Value, state *db.AppState) *resp.Value {
+func HandleCommand(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	// Empty requests are ignored, as in Redis.
+	if len(value.Array) == 0 {
+		return nil
+	}
+	if errReply := checkArgTypes(value); errReply != nil {
+		if c != nil {
+			c.CloseAfterReply = true
+		}
+		return errReply
+	}
+	handler, errReply := lookupCommand(value)
+	if errReply != nil {
+		return errReply
+	}
+
 	cmd := value.Array[0].String
-	handler, ok := CmdHandlers[strings.ToUpper(cmd)]
-	if !ok {
-		fmt.Println("Invalid command: ", cmd)
+	if c != nil && !c.Authenticated && !noAuthAllowed[strings.ToUpper(cmd)] && state.ACL.RequiresAuth() {
+		return &resp.Value{Type: resp.SimpleError, String: "NOAUTH Authentication required."}
+	}
+	if state.Loading.InProgress() && !loadingAllowed[strings.ToUpper(cmd)] {
 		return &resp.Value{
-			Type:   resp.Null,
-			IsNull: true,
+			Type:   resp.SimpleError,
+			String: "LOADING Redis is loading the dataset in memory",
+		}
+	}
+
+	if c != nil && !noAuthAllowed[strings.ToUpper(cmd)] {
+		if errReply := checkACL(c, value, state); errReply != nil {
+			return errReply
+		}
+	}
+
+	if c != nil && !c.RESP3() && !subscribedAllowed[strings.ToUpper(cmd)] && state.PubSub.Count(c) > 0 {
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR Can't execute '" + strings.ToLower(cmd) + "': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context",
+		}
+	}
+
+	// SHUTDOWN itself waits for the other commands to finish, so it must
+	// not hold the gate.
+	if !strings.EqualFold(cmd, CMD_SHUTDOWN) {
+		if !state.Shutdown.Enter() {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR Server is shutting down"}
 		}
+		defer state.Shutdown.Exit()
 	}
 
-	reply := handler(value, state)
+	reply := handler(c, value, state)
+	state.Stats.AddCommand()
+
+	// CLIENT CACHING applies to the command that follows it only.
+	if c != nil && !isClientCaching(value) {
+		state.Tracking.AfterCommand(c)
+	}
 	return reply
 }
 
-func ResolveCommand(value *resp.Value, state *db.AppState) {
-	cmd := value.Array[0].String
-	handler, ok := CmdHandlers[strings.ToUpper(cmd)]
+// checkArgTypes rejects requests whose arguments are not all strings, as
+// only inline commands and arrays of bulk strings are valid requests.
+func checkArgTypes(value *resp.Value) *resp.Value {
+	for _, arg := range value.Array {
+		if (arg.Type != resp.BulkString && arg.Type != resp.SimpleString) || arg.IsNull {
+			return &resp.Value{
+				Type:   resp.SimpleError,
+				String: "ERR Protocol error: expected '$', got '" + string(rune(arg.Type)) + "'",
+			}
+		}
+	}
+	return nil
+}
+
+// lookupCommand returns the handler for the command in value once its
+// name, subcommand and number of arguments are checked against the
+// command table, or the error to reply with.
+func lookupCommand(value *resp.Value) (CmdHandler, *resp.Value) {
+	name := value.Array[0].String
+	meta, ok := commandTable[strings.ToUpper(name)]
 	if !ok {
-		fmt.Println("Invalid command: ", cmd)
+		return nil, &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR unknown command '" + truncate(name, 128) + "', with args beginning with: " + argsPreview(value.Array[1:]),
+		}
+	}
+
+	handler := meta.Handler
+	fullName := strings.ToLower(name)
+	if meta.Subcommands != nil && len(value.Array) >= 2 {
+		sub := strings.ToLower(value.Array[1].String)
+		subMeta, ok := meta.Subcommands[sub]
+		if !ok {
+			return nil, &resp.Value{
+				Type:   resp.SimpleError,
+				String: "ERR unknown subcommand '" + truncate(value.Array[1].String, 128) + "'. Try " + strings.ToUpper(name) + " HELP.",
+			}
+		}
+		meta, fullName = subMeta, fullName+"|"+sub
+	}
+
+	if n := len(value.Array); (meta.Arity > 0 && n != meta.Arity) || n < -meta.Arity {
+		return nil, &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR wrong number of arguments for '" + fullName + "' command",
+		}
+	}
+	return handler, nil
+}
+
+// argsPreview quotes the leading args for an unknown command error, up to
+// about 128 bytes.
+func argsPreview(args []*resp.Value) string {
+	var b strings.Builder
+	for _, arg := range args {
+		if b.Len() >= 128 {
+			break
+		}
+		b.WriteString("'" + truncate(arg.String, 128-b.Len()) + "' ")
+	}
+	return b.String()
+}
+
+// truncate cuts s to at most n bytes and replaces line breaks, which
+// would end an error reply early.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// checkACL returns a NOPERM error if c's user may not run value, after
+// recording the denial for ACL LOG.
+func checkACL(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	name := c.User()
+	reason, object := aclDenial(state.ACL.User(name), value)
+	if reason == "" {
+		return nil
+	}
+	state.ACL.Log.Add(reason, object, name, c.Info())
+	return &resp.Value{Type: resp.SimpleError, String: "NOPERM " + denialMessage(name, reason, object)}
+}
+
+// aclDenial tells why user may not run v, a command in the command table:
+// the reason is "command", "key" or "channel", and object what was denied.
+// Both are empty if v is allowed. A nil user, deleted since the client
+// authenticated, may run nothing.
+func aclDenial(user *acl.User, v *resp.Value) (reason, object string) {
+	meta, cmd, sub := lookupMeta(v)
+	name := cmd
+	if sub != "" {
+		name += "|" + sub
+	}
+	if user == nil {
+		return "command", name
+	}
+
+	if !user.CanRun(cmd, sub, meta.Categories) {
+		return "command", name
+	}
+
+	for _, spec := range meta.Keys {
+		for _, key := range spec.args(v) {
+			if !user.CanAccessKey(key, spec.Read, spec.Write) {
+				return "key", key
+			}
+		}
+	}
+	if meta.Channels != nil {
+		for _, ch := range meta.Channels.args(v) {
+			if !user.CanAccessChannel(ch) {
+				return "channel", ch
+			}
+		}
+	}
+	return "", ""
+}
+
+func denialMessage(user, reason, object string) string {
+	switch reason {
+	case "command":
+		return "User " + user + " has no permissions to run the '" + object + "' command"
+	case "key":
+		return "No permissions to access a key"
+	default:
+		return "No permissions to access a channel"
+	}
+}
+
+// ResolveCommand executes a command with no client attached, as when
+// replaying the AOF.
+func ResolveCommand(value *resp.Value, state *db.AppState) {
+	if len(value.Array) == 0 {
+		return
+	}
+	errReply := checkArgTypes(value)
+	handler, lookupErr := lookupCommand(value)
+	if errReply == nil {
+		errReply = lookupErr
+	}
+	if errReply != nil {
+		fmt.Println("Invalid command:", errReply.String)
+		return
+	}
+	handler(nil, value, state)
+}
+
+// propagate records a write command: changes is added to the dirty counter
+// that drives save points, and the command is appended to the AOF.
+func propagate(value *resp.Value, state *db.AppState, changes int) {
+	if changes == 0 {
+		return
+	}
+	state.RDBStatus.AddDirty(int64(changes))
+	appendAOF(value, state)
+}
+
+// appendAOF logs a write command to the append only file, if one is open.
+func appendAOF(value *resp.Value, state *db.AppState) {
+	if state.Aof == nil {
+		return
+	}
+	if err := state.Aof.Append(value); err != nil {
+		fmt.Println("AOF write error:", err)
+	}
+}
+
+func isClientCaching(value *resp.Value) bool {
+	return len(value.Array) > 1 &&
+		strings.EqualFold(value.Array[0].String, CMD_CLIENT) &&
+		strings.EqualFold(value.Array[1].String, "CACHING")
+}
+
+// lookupKeyRead fetches key on behalf of c. Expired keys are removed and
+// reported as missing; otherwise the read is recorded for client-side caching.
+func lookupKeyRead(c *client.Client, state *db.AppState, key string) (*db.Item, bool) {
+	item, ok := db.DB.Get(key)
+	if ok {
+		if expired, removed := db.DB.TryExpire(key, item); expired {
+			if removed {
+				state.Stats.AddExpired()
+			}
+			state.Tracking.Invalidate(nil, key)
+			item, ok = nil, false
+		}
 	}
-	handler(value, state)
+	state.Tracking.TrackRead(c, key)
+	state.Stats.AddLookup(ok)
+	return item, ok
+}
+
+// signalModifiedKey must be called whenever c changes key, so that clients
+// caching it are told to drop it.
+func signalModifiedKey(c *client.Client, state *db.AppState, key string) {
+	state.Tracking.Invalidate(c, key)
 }
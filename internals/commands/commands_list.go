@@ -21,6 +21,11 @@ const (
 	CMD_SLOWLOG  = "SLOWLOG"
 	CMD_LATENCY  = "LATENCY"
 	CMD_STATS    = "STATS"
+	CMD_ACL      = "ACL"
+	CMD_SAVE     = "SAVE"
+	CMD_BGSAVE   = "BGSAVE"
+	CMD_LASTSAVE = "LASTSAVE"
+	CMD_DBSIZE   = "DBSIZE"
 
 	// Key operations
 	CMD_DEL       = "DEL"
@@ -46,6 +51,7 @@ const (
 	// String commands
 	CMD_SET      = "SET"
 	CMD_GET      = "GET"
+	CMD_MSET     = "MSET"
 	CMD_SETNX    = "SETNX"
 	CMD_SETEX    = "SETEX"
 	CMD_SETRANGE = "SETRANGE"
@@ -205,11 +211,12 @@ const (
 
 var AllCommands = []string{
 	CMD_PING, CMD_ECHO, CMD_QUIT, CMD_AUTH, CMD_SELECT, CMD_FLUSHDB, CMD_FLUSHALL, CMD_INFO, CMD_CLIENT, CMD_SHUTDOWN, CMD_CONFIG,
+	CMD_ACL, CMD_SAVE, CMD_BGSAVE, CMD_LASTSAVE, CMD_DBSIZE,
 	CMD_COMMAND, CMD_ROLE, CMD_MONITOR, CMD_TIME, CMD_DEBUG, CMD_SLOWLOG, CMD_LATENCY, CMD_STATS, CMD_PSUBSCRIBE, CMD_SUBSCRIBE,
 	CMD_UNSUBSCRIBE, CMD_PUBSUB,
 	CMD_DEL, CMD_DUMP, CMD_EXISTS, CMD_EXPIRE, CMD_PEXPIRE, CMD_EXPIREAT, CMD_PEXPIREAT, CMD_TTL, CMD_PTTL, CMD_PERSIST,
 	CMD_RENAME, CMD_RENAMENX, CMD_TYPE, CMD_KEYS, CMD_SCAN, CMD_SORT, CMD_RANDOMKEY, CMD_RESTORE, CMD_MIGRATE,
-	CMD_SET, CMD_GET, CMD_SETNX, CMD_SETEX, CMD_SETRANGE, CMD_GETRANGE, CMD_GETSET, CMD_INCR, CMD_DECR, CMD_INCRBY,
+	CMD_SET, CMD_GET, CMD_MSET, CMD_SETNX, CMD_SETEX, CMD_SETRANGE, CMD_GETRANGE, CMD_GETSET, CMD_INCR, CMD_DECR, CMD_INCRBY,
 	CMD_DECRBY, CMD_APPEND, CMD_STRLEN, CMD_BITCOUNT, CMD_BITOP, CMD_BITPOS, CMD_SETBIT, CMD_GETBIT, CMD_BITFIELD,
 	CMD_HSET, CMD_HGET, CMD_HDEL, CMD_HLEN, CMD_HKEYS, CMD_HVALS, CMD_HGETALL, CMD_HMSET, CMD_HMGET, CMD_HINCRBY,
 	CMD_HEXISTS, CMD_HSCAN, CMD_HSETNX, CMD_HSTRLEN,
@@ -230,4 +237,4 @@ var AllCommands = []string{
 	CMD_BITFIELD_RO, CMD_BITFIELD,
 	CMD_STRALGO, CMD_SWAPDB, CMD_UNLINK, CMD_WAIT, CMD_REPLICAOF, CMD_REPLICA, CMD_PSYNC, CMD_LUA_RO,
 	CMD_LUA_WRITE, CMD_HELLO,
-}
\ No newline at end of file
+}
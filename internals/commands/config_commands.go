@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/tlsconf"
+)
+
+func configCmd(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+	switch strings.ToUpper(args[0].String) {
+	case "GET":
+		return configGet(args[1:], state)
+	case "SET":
+		return configSet(args[1:], state)
+	case "REWRITE":
+		return configRewrite(state)
+	case "RESETSTAT":
+		return configResetStat(state)
+	default:
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR unknown subcommand '" + args[0].String + "'. Try CONFIG HELP.",
+		}
+	}
+}
+
+// configGet implements CONFIG GET pattern [pattern ...]. Patterns are
+// globs matched against the parameter names.
+func configGet(args []*resp.Value, state *db.AppState) *resp.Value {
+	state.ConfigMu.RLock()
+	defer state.ConfigMu.RUnlock()
+
+	reply := &resp.Value{Type: resp.Map, Map: []resp.MapEntry{}}
+	for _, name := range config.Params() {
+		for _, arg := range args {
+			if ok, _ := filepath.Match(strings.ToLower(arg.String), name); ok {
+				val, _ := state.Config.Get(name)
+				reply.AddPair(name, &resp.Value{Type: resp.BulkString, String: val})
+				break
+			}
+		}
+	}
+	return reply
+}
+
+// configSet implements CONFIG SET parameter value [parameter value ...].
+// The parameters are applied together: if any of them is invalid or cannot
+// take effect, the ones already applied are rolled back and none is set.
+func configSet(args []*resp.Value, state *db.AppState) *resp.Value {
+	if len(args)%2 != 0 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR wrong number of arguments for 'config|set' command"}
+	}
+
+	state.ConfigMu.Lock()
+	defer state.ConfigMu.Unlock()
+
+	// Parameters are staged in next, and only those set are copied back.
+	next := state.Config.Clone()
+	var names, vals []string
+	for i := 0; i < len(args); i += 2 {
+		name, val := strings.ToLower(args[i].String), args[i+1].String
+		if slices.Contains(names, name) {
+			return configSetError(name, "duplicate parameter")
+		}
+		if err := next.Set(name, val); err != nil {
+			if errors.Is(err, config.ErrUnknownParam) {
+				return &resp.Value{
+					Type:   resp.SimpleError,
+					String: "ERR Unknown option or number of arguments for CONFIG SET - '" + name + "'",
+				}
+			}
+			return configSetError(name, err.Error())
+		}
+		if err := validateParam(next, name); err != nil {
+			return configSetError(name, err.Error())
+		}
+		names = append(names, name)
+		vals = append(vals, val)
+	}
+
+	// Apply the changes that take effect outside the Config. Should one
+	// fail, those already applied are undone with the old settings.
+	var applied []configHook
+	for _, hook := range configHooks {
+		param := hook.touches(names)
+		if param == "" {
+			continue
+		}
+		if err := hook.apply(state, next); err != nil {
+			for i := len(applied) - 1; i >= 0; i-- {
+				if err := applied[i].apply(state, state.Config); err != nil {
+					log.Println("Failed to roll back CONFIG SET:", err)
+				}
+			}
+			log.Printf("CONFIG SET %s failed: %v\n", param, err)
+			return configSetError(param, hook.failure)
+		}
+		applied = append(applied, hook)
+	}
+
+	for i, name := range names {
+		_ = state.Config.Set(name, vals[i]) // already accepted by next
+	}
+	return &resp.Value{Type: resp.SimpleString, String: "OK"}
+}
+
+// validateParam checks the values Config.Set stores without parsing.
+func validateParam(c *config.Config, name string) error {
+	switch name {
+	case "tls-protocols":
+		_, _, err := tlsconf.ParseProtocols(c.TLSProtocols)
+		return err
+	case "tls-ciphers":
+		_, err := tlsconf.ParseCiphers(c.TLSCiphers)
+		return err
+	}
+	return nil
+}
+
+// A configHook makes parameters take effect on the running server. apply
+// is also called with the old configuration to roll a change back.
+type configHook struct {
+	params  []string
+	apply   func(state *db.AppState, c *config.Config) error
+	failure string // the reason given to the client when apply fails
+}
+
+// touches returns the last of names that h applies, or "" if none.
+func (h configHook) touches(names []string) string {
+	param := ""
+	for _, name := range names {
+		if slices.Contains(h.params, name) {
+			param = name
+		}
+	}
+	return param
+}
+
+// configHooks run in order, those that may fail first.
+var configHooks = []configHook{
+	{
+		// Certificates are reloaded only once TLS is in use; the new ones
+		// apply to connections made from now on.
+		params: []string{
+			"tls-cert-file", "tls-key-file", "tls-ca-cert-file",
+			"tls-auth-clients", "tls-protocols", "tls-ciphers",
+		},
+		apply: func(state *db.AppState, c *config.Config) error {
+			if !state.TLS.Loaded() {
+				return nil
+			}
+			return state.TLS.Load(c)
+		},
+		failure: "Unable to update TLS configuration. Check server logs.",
+	},
+	{
+		// Turning the AOF on writes a fresh base from the dataset.
+		params: []string{"appendonly"},
+		apply: func(state *db.AppState, c *config.Config) error {
+			if c.AOFenabled {
+				return state.Aof.Start()
+			}
+			if err := state.Aof.Sync(); err != nil {
+				return err
+			}
+			return state.Aof.Close()
+		},
+		failure: "Unable to turn on AOF. Check server logs.",
+	},
+	{
+		params: []string{"appendfsync"},
+		apply: func(state *db.AppState, c *config.Config) error {
+			state.Aof.SetFsync(c.AOFfsync)
+			return nil
+		},
+	},
+	{
+		params: []string{"save"},
+		apply: func(state *db.AppState, c *config.Config) error {
+			state.Saver.SetRules(c.RDB)
+			return nil
+		},
+	},
+	{
+		params: []string{"requirepass"},
+		apply: func(state *db.AppState, c *config.Config) error {
+			if !c.Requirepass {
+				return state.ACL.SetUser(acl.DefaultUser, "resetpass", "nopass")
+			}
+			return state.ACL.SetUser(acl.DefaultUser, "resetpass", ">"+c.Password)
+		},
+	},
+	{
+		params: []string{"acllog-max-len"},
+		apply: func(state *db.AppState, c *config.Config) error {
+			state.ACL.Log.SetMaxLen(c.ACLLogMaxLen)
+			return nil
+		},
+	},
+}
+
+// configRewrite implements CONFIG REWRITE: the configuration file is
+// updated to the current settings, keeping its comments. Users are
+// written as user directives unless they live in an aclfile.
+func configRewrite(state *db.AppState) *resp.Value {
+	state.ConfigMu.RLock()
+	conf := state.Config.Clone()
+	state.ConfigMu.RUnlock()
+
+	if conf.File == "" {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR The server is running without a config file"}
+	}
+	if conf.ACLFile == "" {
+		conf.Users = nil
+		for _, u := range state.ACL.Users() {
+			rules := append([]string{u.Name, "reset"}, strings.Fields(u.Rules())...)
+			conf.Users = append(conf.Users, rules)
+		}
+	}
+
+	if err := config.Rewrite(conf); err != nil {
+		log.Println("CONFIG REWRITE failed:", err)
+		return &resp.Value{Type: resp.SimpleError, String: "ERR Rewriting config file: " + err.Error()}
+	}
+	return &resp.Value{Type: resp.SimpleString, String: "OK"}
+}
+
+// configResetStat implements CONFIG RESETSTAT, clearing the counters
+// reported by INFO stats.
+func configResetStat(state *db.AppState) *resp.Value {
+	state.Stats.Reset()
+	return &resp.Value{Type: resp.SimpleString, String: "OK"}
+}
+
+func configSetError(param, reason string) *resp.Value {
+	return &resp.Value{
+		Type:   resp.SimpleError,
+		String: "ERR CONFIG SET failed (possibly related to argument '" + param + "') - " + reason,
+	}
+}
@@ -1,13 +1,163 @@
 package commands
 
 import (
+	"strconv"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
-func command(v *resp.Value, state *db.AppState) *resp.Value {
-	return &resp.Value{
-		Type:   resp.SimpleString,
-		String: "OK",
+func ping(c *client.Client, v *resp.Value, state *db.AppState) *resp.Value {
+	args := v.Array[1:]
+	if c != nil && !c.RESP3() && state.PubSub.Count(c) > 0 && len(args) <= 1 {
+		// RESP2 clients in subscribed mode can only read pub/sub shaped
+		// replies.
+		msg := ""
+		if len(args) == 1 {
+			msg = args[0].String
+		}
+		return &resp.Value{Type: resp.Array, Array: []*resp.Value{
+			{Type: resp.BulkString, String: "pong"},
+			{Type: resp.BulkString, String: msg},
+		}}
+	}
+	switch len(args) {
+	case 0:
+		return &resp.Value{Type: resp.SimpleString, String: "PONG"}
+	case 1:
+		return &resp.Value{Type: resp.BulkString, String: args[0].String}
+	default:
+		return &resp.Value{Type: resp.SimpleError, String: "ERR wrong number of arguments for 'ping' command"}
+	}
+}
+
+// auth implements AUTH [username] password. Without a username the client
+// logs in as the default user.
+func auth(c *client.Client, v *resp.Value, state *db.AppState) *resp.Value {
+	args := v.Array[1:]
+	switch len(args) {
+	case 1:
+		if state.ACL.User(acl.DefaultUser).NoPass {
+			return &resp.Value{
+				Type:   resp.SimpleError,
+				String: "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?",
+			}
+		}
+		if errReply := authenticate(c, state, acl.DefaultUser, args[0].String); errReply != nil {
+			return errReply
+		}
+	case 2:
+		if errReply := authenticate(c, state, args[0].String, args[1].String); errReply != nil {
+			return errReply
+		}
+	default:
+		return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+	}
+	return resp.OK
+}
+
+// authenticate logs c in as user, for AUTH and HELLO. It returns the error
+// to reply with, or nil on success. Hosts that keep failing are refused
+// for a while without their password being checked.
+func authenticate(c *client.Client, state *db.AppState, user, pass string) *resp.Value {
+	host := c.Host()
+	if !state.AuthLimiter.Allow(host) {
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR Too many failed authentication attempts, try again later",
+		}
+	}
+
+	if _, err := state.ACL.Authenticate(user, pass); err != nil {
+		state.AuthLimiter.Fail(host)
+		state.ACL.Log.Add("auth", "AUTH", user, c.Info())
+		return &resp.Value{Type: resp.SimpleError, String: err.Error()}
+	}
+
+	state.AuthLimiter.Succeed(host)
+	c.SetUser(user)
+	return nil
+}
+
+// quit replies OK and closes the connection.
+func quit(c *client.Client, v *resp.Value, state *db.AppState) *resp.Value {
+	if c != nil {
+		c.CloseAfterReply = true
+	}
+	return resp.OK
+}
+
+// hello implements HELLO [protover [AUTH username password] [SETNAME name]].
+// It switches the connection's protocol and replies with the server info
+// map, which RESP2 clients receive as a flat array.
+func hello(c *client.Client, v *resp.Value, state *db.AppState) *resp.Value {
+	args := v.Array[1:]
+	proto := c.Protocol
+
+	if len(args) > 0 {
+		ver, err := strconv.Atoi(args[0].String)
+		if err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR Protocol version is not an integer or out of range"}
+		}
+		if ver != 2 && ver != 3 {
+			return &resp.Value{Type: resp.SimpleError, String: "NOPROTO unsupported protocol version"}
+		}
+		proto = ver
+		args = args[1:]
+	}
+
+	// Every option is checked before any takes effect, so a rejected
+	// HELLO neither logs the client in nor renames it.
+	var user, pass, name string
+	auth, setName := false, false
+	for i := 0; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i].String); {
+		case opt == "AUTH" && i+2 < len(args):
+			user, pass, auth = args[i+1].String, args[i+2].String, true
+			i += 2
+		case opt == "SETNAME" && i+1 < len(args):
+			name, setName = args[i+1].String, true
+			if strings.ContainsAny(name, " \n") {
+				return &resp.Value{
+					Type:   resp.SimpleError,
+					String: "ERR Client names cannot contain spaces, newlines or special characters.",
+				}
+			}
+			i++
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR Syntax error in HELLO option '" + args[i].String + "'"}
+		}
 	}
+
+	if auth {
+		if errReply := authenticate(c, state, user, pass); errReply != nil {
+			return errReply
+		}
+	}
+	if !c.Authenticated && state.ACL.RequiresAuth() {
+		return &resp.Value{
+			Type: resp.SimpleError,
+			String: "NOAUTH HELLO must be called with the client already authenticated, otherwise the " +
+				"HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and " +
+				"select the RESP protocol version at the same time",
+		}
+	}
+
+	c.SetProtocol(proto)
+	if setName {
+		c.SetName(name)
+	}
+
+	reply := &resp.Value{Type: resp.Map}
+	reply.AddPair("server", &resp.Value{Type: resp.BulkString, String: "redis"})
+	reply.AddPair("version", &resp.Value{Type: resp.BulkString, String: db.RedisVersion})
+	reply.AddPair("proto", &resp.Value{Type: resp.Integer, Integer: int64(c.Protocol)})
+	reply.AddPair("id", &resp.Value{Type: resp.Integer, Integer: c.ID})
+	reply.AddPair("mode", &resp.Value{Type: resp.BulkString, String: "standalone"})
+	reply.AddPair("role", &resp.Value{Type: resp.BulkString, String: "master"})
+	reply.AddPair("modules", &resp.Value{Type: resp.Array, Array: []*resp.Value{}})
+	return reply
 }
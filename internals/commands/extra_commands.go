@@ -1,72 +1,121 @@
 package commands
 
 import (
+	"log"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
-func save(value *resp.Value, state *db.AppState) *resp.Value {
-	args := value.Array[1:]
-	if len(args) != 0 {
+func save(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	if err := db.SaveRDB(state); err != nil {
+		log.Println("SAVE failed:", err)
 		return &resp.Value{
-			Type:   resp.BulkString,
-			String: "ERR Invalid number of arguments for KEYS",
+			Type:   resp.SimpleError,
+			String: "ERR " + err.Error(),
 		}
 	}
-
-	db.SaveRDB(state)
 	return &resp.Value{
 		Type: resp.SimpleString,
 		String: "OK",
 	}
 }
 
-func bgsave(value *resp.Value, state *db.AppState) *resp.Value {
-	if state.BgSaveRunning {
-		return &resp.Value{
-			Type: resp.SimpleError,
-			String: "ERR background saving already in progress",
-		}
+func lastsave(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	return &resp.Value{
+		Type:    resp.Integer,
+		Integer: state.RDBStatus.Snapshot().LastSave.Unix(),
 	}
+}
 
+func bgsave(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
-	if len(args) != 0 {
+	if len(args) > 1 {
 		return &resp.Value{
-			Type:   resp.BulkString,
-			String: "ERR Invalid number of arguments for KEYS",
+			Type:   resp.SimpleError,
+			String: "ERR wrong number of arguments for 'bgsave' command",
 		}
 	}
-	
-	copy := db.DB.GetItems()
-	state.DBCopy = *copy
-	go func() {
-		defer func() {
-			state.BgSaveRunning = false
-			state.DBCopy = nil
-		}()
 
-		db.SaveRDB(state)
-	}()
+	schedule := false
+	if len(args) == 1 {
+		if !strings.EqualFold(args[0].String, "SCHEDULE") {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+		schedule = true
+	}
+
+	scheduled, err := db.BgSaveRDB(state, schedule)
+	if err != nil {
+		return &resp.Value{
+			Type:   resp.SimpleError,
+			String: "ERR " + err.Error(),
+		}
+	}
 
-	db.SaveRDB(state)
+	if scheduled {
+		return &resp.Value{
+			Type:   resp.SimpleString,
+			String: "Background saving scheduled",
+		}
+	}
 	return &resp.Value{
-		Type: resp.SimpleString,
-		String: "OK",
+		Type:   resp.SimpleString,
+		String: "Background saving started",
 	}
 }
 
-func flushdb(value *resp.Value, state *db.AppState) *resp.Value {
-	db.DB.Reset()
-	return &resp.Value{
-		Type: resp.SimpleString,
-		String: "OK",
+// shutdown implements SHUTDOWN [NOSAVE|SAVE] [NOW] [FORCE] [ABORT]. On
+// success the server closes the connection without replying.
+func shutdown(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	var opts db.ShutdownOptions
+	abort := false
+	for _, arg := range value.Array[1:] {
+		switch strings.ToUpper(arg.String) {
+		case "SAVE":
+			opts.Save = true
+		case "NOSAVE":
+			opts.NoSave = true
+		case "NOW":
+			opts.Now = true
+		case "FORCE":
+			opts.Force = true
+		case "ABORT":
+			abort = true
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+	}
+	if (opts.Save && opts.NoSave) || (abort && len(value.Array) > 2) {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
 	}
+
+	if abort {
+		if err := state.Shutdown.Abort(); err != nil {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR " + err.Error()}
+		}
+		return resp.OK
+	}
+
+	if err := <-state.Shutdown.Request(opts); err != nil {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR " + err.Error()}
+	}
+	return nil
+}
+
+func flushdb(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	changes := db.DB.GetLen()
+	db.DB.Reset()
+	state.Tracking.InvalidateAll()
+	propagate(value, state, max(changes, 1))
+	return resp.OK
 }
 
-func dbsize(value *resp.Value, state *db.AppState) *resp.Value {
+func dbsize(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	length := db.DB.GetLen()
 	return &resp.Value{
 		Type: resp.Integer,
@@ -74,10 +123,10 @@ func dbsize(value *resp.Value, state *db.AppState) *resp.Value {
 	}
 }
 
-func expire(value *resp.Value, state *db.AppState) *resp.Value {
+func expire(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
-	if len(args) != 2 {
-		return &resp.Value{Type: resp.SimpleError, String: "ERR invalid number of arguments for 'EXPIRE' command"}
+	if len(args) > 2 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR Unsupported option " + args[2].String}
 	}
 
 	k := args[0].String
@@ -88,24 +137,40 @@ func expire(value *resp.Value, state *db.AppState) *resp.Value {
 		return &resp.Value{Type: resp.SimpleError, String: "ERR invalid expiry value"}
 	}
 
-	key, ok := db.DB.Get(k)
-	if !ok {
-		return &resp.Value{Type: resp.Integer, Integer: 0}
+	return setExpiry(c, state, k, time.Now().Add(time.Second*time.Duration(expSecs)))
+}
+
+// pexpireat implements PEXPIREAT key unix-time-milliseconds, which is also
+// how every expiry is written to the AOF.
+func pexpireat(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+	if len(args) > 2 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR Unsupported option " + args[2].String}
 	}
-	key.Expires = time.Now().Add(time.Second * time.Duration(expSecs))
 
-	return &resp.Value{Type: resp.Integer, Integer: 1}
+	ms, err := strconv.ParseInt(args[1].String, 10, 64)
+	if err != nil {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR value is not an integer or out of range"}
+	}
+	return setExpiry(c, state, args[0].String, time.UnixMilli(ms))
 }
 
-func ttl(value *resp.Value, state *db.AppState) *resp.Value {
-	args := value.Array[1:]
-	if len(args) != 1 {
-		return &resp.Value{Type: resp.SimpleError, String: "ERR invalid number of arguments for 'TTL' command"}
+// setExpiry makes key expire at the given time. The change is propagated
+// as PEXPIREAT whatever command made it.
+func setExpiry(c *client.Client, state *db.AppState, key string, at time.Time) *resp.Value {
+	if !db.DB.SetExpiry(key, at) {
+		return &resp.Value{Type: resp.Integer, Integer: 0}
 	}
+	signalModifiedKey(c, state, key)
+	propagate(db.PExpireAtCommand(key, at), state, 1)
 
-	k := args[0].String
+	return &resp.Value{Type: resp.Integer, Integer: 1}
+}
+
+func ttl(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	k := value.Array[1].String
 
-	item, ok := db.DB.Get(k)
+	item, ok := lookupKeyRead(c, state, k)
 	if !ok {
 		return &resp.Value{Type: resp.Integer, Integer: -2}
 	}
@@ -115,11 +180,6 @@ func ttl(value *resp.Value, state *db.AppState) *resp.Value {
 		return &resp.Value{Type: resp.Integer, Integer: -1}
 	}
 
-	expired := db.DB.TryExpire(k, item)
-	if expired {
-		return &resp.Value{Type: resp.Integer, Integer: -2}
-	}
-
 	expSecs := int(time.Until(expires).Seconds())
 	return &resp.Value{Type: resp.Integer, Integer: int64(expSecs)}
 }
\ No newline at end of file
@@ -0,0 +1,266 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+type infoField struct {
+	name  string
+	value string
+}
+
+type infoSection struct {
+	name   string
+	title  string
+	fields func(state *db.AppState) []infoField
+}
+
+// infoSections are the INFO sections, in the order Redis prints them.
+var infoSections = []infoSection{
+	{name: "server", title: "Server", fields: infoServer},
+	{name: "clients", title: "Clients", fields: infoClients},
+	{name: "memory", title: "Memory", fields: infoMemory},
+	{name: "persistence", title: "Persistence", fields: infoPersistence},
+	{name: "stats", title: "Stats", fields: infoStats},
+	{name: "replication", title: "Replication", fields: infoReplication},
+	{name: "keyspace", title: "Keyspace", fields: infoKeyspace},
+}
+
+func info(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+
+	wanted := map[string]bool{}
+	for _, arg := range args {
+		wanted[strings.ToLower(arg.String)] = true
+	}
+	all := len(wanted) == 0 || wanted["all"] || wanted["default"] || wanted["everything"]
+
+	var sb strings.Builder
+	for _, section := range infoSections {
+		if !all && !wanted[section.name] {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("# " + section.title + "\r\n")
+		for _, f := range section.fields(state) {
+			sb.WriteString(f.name + ":" + f.value + "\r\n")
+		}
+	}
+
+	return &resp.Value{
+		Type:   resp.BulkString,
+		String: sb.String(),
+	}
+}
+
+func infoServer(state *db.AppState) []infoField {
+	state.ConfigMu.RLock()
+	port, file := state.Config.Port, state.Config.File
+	state.ConfigMu.RUnlock()
+
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	executable, _ := os.Executable()
+	uptime := time.Since(state.Started)
+
+	return []infoField{
+		{"redis_version", db.RedisVersion},
+		{"redis_git_sha1", "00000000"},
+		{"redis_git_dirty", "0"},
+		{"redis_mode", "standalone"},
+		{"os", runtime.GOOS + " " + runtime.GOARCH},
+		{"arch_bits", strconv.Itoa(strconv.IntSize)},
+		{"go_version", runtime.Version()},
+		{"process_id", strconv.Itoa(os.Getpid())},
+		{"run_id", state.RunID},
+		{"tcp_port", strconv.Itoa(port)},
+		{"server_time_usec", strconv.FormatInt(time.Now().UnixMicro(), 10)},
+		{"uptime_in_seconds", strconv.FormatInt(int64(uptime.Seconds()), 10)},
+		{"uptime_in_days", strconv.FormatInt(int64(uptime.Hours()/24), 10)},
+		{"executable", executable},
+		{"config_file", file},
+	}
+}
+
+func infoClients(state *db.AppState) []infoField {
+	return []infoField{
+		{"connected_clients", strconv.Itoa(state.Clients.Len())},
+		{"blocked_clients", "0"},
+		{"tracking_clients", strconv.Itoa(state.Tracking.Len())},
+		{"pubsub_clients", strconv.Itoa(state.PubSub.NumClients())},
+	}
+}
+
+// infoMemory reports the Go heap in use as used_memory, and the memory
+// obtained from the OS as used_memory_rss.
+func infoMemory(state *db.AppState) []infoField {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	used := int64(mem.HeapAlloc)
+	peak := state.Stats.ObserveMemory(used)
+
+	return []infoField{
+		{"used_memory", strconv.FormatInt(used, 10)},
+		{"used_memory_human", bytesToHuman(used)},
+		{"used_memory_rss", strconv.FormatUint(mem.Sys, 10)},
+		{"used_memory_rss_human", bytesToHuman(int64(mem.Sys))},
+		{"used_memory_peak", strconv.FormatInt(peak, 10)},
+		{"used_memory_peak_human", bytesToHuman(peak)},
+		{"maxmemory", "0"},
+		{"maxmemory_human", "0B"},
+		{"maxmemory_policy", "noeviction"},
+		{"mem_allocator", "go"},
+	}
+}
+
+func infoPersistence(state *db.AppState) []infoField {
+	status := state.RDBStatus.Snapshot()
+
+	lastStatus := "ok"
+	if !status.LastOK {
+		lastStatus = "err"
+	}
+
+	lastDuration := "-1"
+	if status.LastDuration > 0 {
+		lastDuration = strconv.Itoa(int(status.LastDuration.Seconds()))
+	}
+
+	currentDuration := "-1"
+	if !status.CurrentStart.IsZero() {
+		currentDuration = strconv.Itoa(int(time.Since(status.CurrentStart).Seconds()))
+	}
+
+	fields := infoLoading(state)
+	return append(fields, []infoField{
+		{"rdb_changes_since_last_save", strconv.FormatInt(status.Dirty, 10)},
+		{"rdb_bgsave_in_progress", boolToInfo(status.InProgress)},
+		{"rdb_bgsave_scheduled", boolToInfo(status.Scheduled)},
+		{"rdb_last_save_time", strconv.FormatInt(status.LastSave.Unix(), 10)},
+		{"rdb_last_bgsave_status", lastStatus},
+		{"rdb_last_bgsave_time_sec", lastDuration},
+		{"rdb_current_bgsave_time_sec", currentDuration},
+		{"rdb_saves", strconv.FormatInt(status.Saves, 10)},
+		{"aof_enabled", boolToInfo(state.Aof.Enabled())},
+		{"aof_rewrite_in_progress", "0"},
+		{"aof_rewrite_scheduled", "0"},
+	}...)
+}
+
+// infoStats reports the counters in state.Stats. Without maxclients and
+// maxmemory no connection is rejected and no key evicted.
+func infoStats(state *db.AppState) []infoField {
+	stats := state.Stats.Snapshot()
+	return []infoField{
+		{"total_connections_received", strconv.FormatInt(stats.Connections, 10)},
+		{"total_commands_processed", strconv.FormatInt(stats.Commands, 10)},
+		{"rejected_connections", "0"},
+		{"expired_keys", strconv.FormatInt(stats.ExpiredKeys, 10)},
+		{"evicted_keys", "0"},
+		{"keyspace_hits", strconv.FormatInt(stats.KeyspaceHits, 10)},
+		{"keyspace_misses", strconv.FormatInt(stats.KeyspaceMisses, 10)},
+		{"pubsub_channels", strconv.Itoa(state.PubSub.NumChannels())},
+		{"pubsub_patterns", "0"},
+	}
+}
+
+// infoReplication describes a master without replicas: replication is not
+// supported.
+func infoReplication(state *db.AppState) []infoField {
+	return []infoField{
+		{"role", "master"},
+		{"connected_slaves", "0"},
+		{"master_failover_state", "no-failover"},
+		{"master_replid", state.RunID},
+		{"master_replid2", strings.Repeat("0", 40)},
+		{"master_repl_offset", "0"},
+		{"second_repl_offset", "-1"},
+		{"repl_backlog_active", "0"},
+		{"repl_backlog_size", "1048576"},
+		{"repl_backlog_first_byte_offset", "0"},
+		{"repl_backlog_histlen", "0"},
+	}
+}
+
+// infoKeyspace lists db0, the only database, unless it is empty.
+func infoKeyspace(state *db.AppState) []infoField {
+	keys := db.DB.GetLen()
+	if keys == 0 {
+		return nil
+	}
+	expires := db.DB.ExpiresLen()
+	return []infoField{
+		{"db0", "keys=" + strconv.Itoa(keys) + ",expires=" + strconv.Itoa(expires) + ",avg_ttl=0"},
+	}
+}
+
+func infoLoading(state *db.AppState) []infoField {
+	loading := state.Loading.Snapshot()
+	fields := []infoField{
+		{"loading", boolToInfo(loading.Loading)},
+		{"async_loading", "0"},
+	}
+
+	if loading.Loading {
+		elapsed := time.Since(loading.Start).Seconds()
+		perc, eta := 0.0, 1
+		if loading.TotalBytes > 0 {
+			perc = float64(loading.LoadedBytes) / float64(loading.TotalBytes) * 100
+		}
+		if loading.LoadedBytes > 0 {
+			rate := float64(loading.LoadedBytes) / max(elapsed, 0.001)
+			eta = int(float64(loading.TotalBytes-loading.LoadedBytes) / rate)
+		}
+
+		fields = append(fields, []infoField{
+			{"loading_start_time", strconv.FormatInt(loading.Start.Unix(), 10)},
+			{"loading_total_bytes", strconv.FormatInt(loading.TotalBytes, 10)},
+			{"loading_loaded_bytes", strconv.FormatInt(loading.LoadedBytes, 10)},
+			{"loading_loaded_perc", strconv.FormatFloat(perc, 'f', 2, 64)},
+			{"loading_eta_seconds", strconv.Itoa(eta)},
+		}...)
+	}
+
+	return append(fields, []infoField{
+		{"loading_last_duration_ms", strconv.FormatInt(loading.LastDuration.Milliseconds(), 10)},
+		{"rdb_last_load_keys_loaded", strconv.FormatInt(loading.KeysLoaded, 10)},
+		{"rdb_last_load_keys_expired", strconv.FormatInt(loading.KeysExpired, 10)},
+	}...)
+}
+
+// bytesToHuman formats n as Redis does in INFO, e.g. 1.50M.
+func bytesToHuman(n int64) string {
+	units := []string{"B", "K", "M", "G", "T", "P"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	return strconv.FormatFloat(f, 'f', 2, 64) + units[i]
+}
+
+func boolToInfo(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
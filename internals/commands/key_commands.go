@@ -1,37 +1,41 @@
 package commands
 
 import (
-	"log"
-	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/glob"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
-func del(value *resp.Value, state *db.AppState) *resp.Value {
+func del(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
 	var keysDeleted int = 0
 
 	for _, arg := range args {
-		_, exists := db.DB.Get(arg.String)
-		if exists {
-			db.DB.Del(arg.String)
+		// Only the DEL that removed the key counts it.
+		if db.DB.Del(arg.String) {
+			signalModifiedKey(c, state, arg.String)
 			keysDeleted++
 		}
 	}
 
+	propagate(value, state, keysDeleted)
+
 	return &resp.Value{
 		Type:    resp.Integer,
 		Integer: int64(keysDeleted),
 	}
 }
 
-func exists(value *resp.Value, state *db.AppState) *resp.Value {
+func exists(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
 	var keysDetected int = 0
 
 	for _, arg := range args {
-		_, exists := db.DB.Get(arg.String)
+		_, exists := lookupKeyRead(c, state, arg.String)
 		if exists {
 			keysDetected++
 		}
@@ -43,25 +47,11 @@ func exists(value *resp.Value, state *db.AppState) *resp.Value {
 	}
 }
 
-func keys(value *resp.Value, state *db.AppState) *resp.Value {
-	args := value.Array[1:]
-	if len(args) != 1 {
-		return &resp.Value{
-			Type:   resp.BulkString,
-			String: "ERR Invalid number of arguments for KEYS",
-		}
-	}
-
-	pattern := args[0].String
+func keys(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	pattern := value.Array[1].String
 	var matches []string
 	for _, key := range *db.DB.GetKeys() {
-		match, err := filepath.Match(pattern, key)
-		if err != nil {
-			log.Printf("error matching keys: (pattern: %s), (key: %s) - %v\n", pattern, key, err)
-			continue
-		}
-
-		if match {
+		if glob.Match(pattern, key) {
 			matches = append(matches, key)
 		}
 	}
@@ -72,3 +62,79 @@ func keys(value *resp.Value, state *db.AppState) *resp.Value {
 	}
 	return &reply
 }
+
+func rename(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+
+	src, dst := args[0].String, args[1].String
+	found := false
+	db.DB.WithKeys([]string{src, dst}, func(tx db.Tx) {
+		item, ok := tx.Get(src)
+		if !ok || item.IsExpired() {
+			return
+		}
+		found = true
+		if src == dst {
+			return
+		}
+		tx.Del(src)
+		tx.Put(dst, item)
+	})
+	if !found {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR no such key"}
+	}
+
+	if src != dst {
+		signalModifiedKey(c, state, src)
+		signalModifiedKey(c, state, dst)
+	}
+	propagate(value, state, 1)
+
+	return resp.OK
+}
+
+// scan implements SCAN cursor [MATCH pattern] [COUNT count]. The cursor is
+// a hash slot number, see db.Database.Scan.
+func scan(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+
+	cursor, err := strconv.Atoi(args[0].String)
+	if err != nil || cursor < 0 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR invalid cursor"}
+	}
+
+	pattern, count := "", 10
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+		switch strings.ToUpper(args[i].String) {
+		case "MATCH":
+			pattern = args[i+1].String
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1].String)
+			if err != nil || count < 1 {
+				return &resp.Value{Type: resp.SimpleError, String: "ERR value is not an integer or out of range"}
+			}
+		default:
+			return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
+		}
+	}
+
+	next, found := db.DB.Scan(cursor, count)
+	keys := &resp.Value{Type: resp.Array, Array: make([]*resp.Value, 0, len(found))}
+	for _, key := range found {
+		if pattern != "" && !glob.Match(pattern, key) {
+			continue
+		}
+		if item, ok := db.DB.Get(key); !ok || item.IsExpired() {
+			continue
+		}
+		keys.Array = append(keys.Array, &resp.Value{Type: resp.BulkString, String: key})
+	}
+
+	return &resp.Value{Type: resp.Array, Array: []*resp.Value{
+		{Type: resp.BulkString, String: strconv.Itoa(next)},
+		keys,
+	}}
+}
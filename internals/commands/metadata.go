@@ -0,0 +1,397 @@
+package commands
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// CommandMeta is a command table entry: how to run the command and what
+// the server knows about it. COMMAND reports it, and ACL rules match on its
+// categories and on the arguments it marks as keys or channels.
+type CommandMeta struct {
+	// Handler runs the command, subcommands included. It is only set on
+	// top-level commands.
+	Handler CmdHandler
+	// Arity is the exact number of arguments including the command name,
+	// or minus the minimum number if it is negative.
+	Arity int
+	// Flags are Redis command flags such as write, readonly and fast.
+	Flags      []string
+	Categories []string
+	Keys       []KeySpec
+	Channels   *ArgRange
+	// Group and Summary document the command for COMMAND DOCS.
+	Group   string
+	Summary string
+	// Subcommands of container commands such as CONFIG, by lowercase name.
+	Subcommands map[string]*CommandMeta
+}
+
+// ArgRange selects the arguments First, First+Step, ... up to Last, where
+// argument 0 is the command name and a negative Last counts from the end.
+type ArgRange struct {
+	First, Last, Step int
+}
+
+// KeySpec is a range of key arguments and how the command accesses them.
+type KeySpec struct {
+	ArgRange
+	Read, Write bool
+}
+
+// args returns the arguments of v that r selects.
+func (r ArgRange) args(v *resp.Value) []string {
+	last := r.Last
+	if last < 0 {
+		last += len(v.Array)
+	}
+	step := max(r.Step, 1)
+
+	var out []string
+	for i := r.First; i <= last && i < len(v.Array); i += step {
+		out = append(out, v.Array[i].String)
+	}
+	return out
+}
+
+var (
+	readKey   = []KeySpec{{ArgRange: ArgRange{1, 1, 1}, Read: true}}
+	writeKey  = []KeySpec{{ArgRange: ArgRange{1, 1, 1}, Write: true}}
+	readKeys  = []KeySpec{{ArgRange: ArgRange{1, -1, 1}, Read: true}}
+	writeKeys = []KeySpec{{ArgRange: ArgRange{1, -1, 1}, Write: true}}
+)
+
+// Flags shared by many commands.
+var (
+	flagsAdmin    = []string{"admin", "noscript", "loading", "stale"}
+	flagsLoading  = []string{"loading", "stale"}
+	flagsNoScript = []string{"noscript", "loading", "stale"}
+)
+
+// commandTable maps each command name to how to run it and what the server
+// knows about it. Dispatch, arity checks, ACL rules and COMMAND all read it.
+// It is filled in by init because the COMMAND and ACL handlers read it too.
+var commandTable map[string]*CommandMeta
+
+func init() {
+	commandTable = map[string]*CommandMeta{
+		// Connection Commands
+		CMD_COMMAND: {
+			Handler: command, Arity: -1, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+			Group: "server", Summary: "Returns detailed information about all commands.",
+			Subcommands: map[string]*CommandMeta{
+				"count": {
+					Arity: 2, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+					Summary: "Returns a count of commands.",
+				},
+				"docs": {
+					Arity: -2, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+					Summary: "Returns documentary information about one, multiple or all commands.",
+				},
+				"getkeys": {
+					Arity: -3, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+					Summary: "Extracts the key names from an arbitrary command.",
+				},
+				"info": {
+					Arity: -2, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+					Summary: "Returns information about one, multiple or all commands.",
+				},
+				"list": {
+					Arity: -2, Flags: flagsLoading, Categories: []string{"slow", "connection"},
+					Summary: "Returns a list of command names.",
+				},
+			},
+		},
+		CMD_PING: {
+			Handler: ping, Arity: -1, Flags: []string{"fast"}, Categories: []string{"fast", "connection"},
+			Group: "connection", Summary: "Returns the server's liveliness response.",
+		},
+		CMD_HELLO: {
+			Handler: hello, Arity: -1, Flags: []string{"noscript", "loading", "stale", "fast"}, Categories: []string{"fast", "connection"},
+			Group: "connection", Summary: "Handshakes with the Redis server.",
+		},
+		CMD_AUTH: {
+			Handler: auth, Arity: -2, Flags: []string{"noscript", "loading", "stale", "fast"}, Categories: []string{"fast", "connection"},
+			Group: "connection", Summary: "Authenticates the connection.",
+		},
+		CMD_QUIT: {
+			Handler: quit, Arity: -1, Flags: []string{"noscript", "loading", "stale", "fast"}, Categories: []string{"fast", "connection"},
+			Group: "connection", Summary: "Closes the connection.",
+		},
+		CMD_CLIENT: {
+			Handler: clientCmd, Arity: -2, Group: "connection", Summary: "A container for client connection commands.",
+			Subcommands: map[string]*CommandMeta{
+				"id": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Returns the unique client ID of the connection.",
+				},
+				"info": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Returns information about the connection.",
+				},
+				"list": {
+					Arity: -2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous", "connection"},
+					Summary: "Lists open connections.",
+				},
+				"getname": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Returns the name of the connection.",
+				},
+				"setname": {
+					Arity: 3, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Sets the connection name.",
+				},
+				"tracking": {
+					Arity: -3, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Controls server-assisted client-side caching for the connection.",
+				},
+				"caching": {
+					Arity: 3, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Instructs the server whether to track the keys in the next request.",
+				},
+				"getredir": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Returns the client ID to which the connection's tracking notifications are redirected.",
+				},
+				"trackinginfo": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow", "connection"},
+					Summary: "Returns information about server-assisted client-side caching for the connection.",
+				},
+			},
+		},
+		CMD_ACL: {
+			Handler: aclCmd, Arity: -2, Group: "server", Summary: "A container for Access List Control commands.",
+			Subcommands: map[string]*CommandMeta{
+				"cat": {
+					Arity: -2, Flags: flagsNoScript, Categories: []string{"slow"},
+					Summary: "Lists the ACL categories, or the commands inside a category.",
+				},
+				"deluser": {
+					Arity: -3, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Deletes ACL users, and terminates their connections.",
+				},
+				"dryrun": {
+					Arity: -4, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Simulates the execution of a command by a user, without executing the command.",
+				},
+				"getuser": {
+					Arity: 3, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Lists the ACL rules of a user.",
+				},
+				"list": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Dumps the effective rules in ACL file format.",
+				},
+				"load": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Reloads the rules from the configured ACL file.",
+				},
+				"log": {
+					Arity: -2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Lists recent security events generated due to ACL rules.",
+				},
+				"save": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Saves the effective ACL rules in the configured ACL file.",
+				},
+				"setuser": {
+					Arity: -3, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Creates and modifies an ACL user and its rules.",
+				},
+				"users": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Lists all ACL users.",
+				},
+				"whoami": {
+					Arity: 2, Flags: flagsNoScript, Categories: []string{"slow"},
+					Summary: "Returns the authenticated username of the current connection.",
+				},
+			},
+		},
+
+		// Pub/Sub Commands
+		CMD_SUBSCRIBE: {
+			Handler: subscribe, Arity: -2, Flags: []string{"pubsub", "noscript", "loading", "stale"}, Categories: []string{"pubsub", "slow"},
+			Channels: &ArgRange{1, -1, 1},
+			Group:    "pubsub", Summary: "Listens for messages published to channels.",
+		},
+		CMD_UNSUBSCRIBE: {
+			Handler: unsubscribe, Arity: -1, Flags: []string{"pubsub", "noscript", "loading", "stale"}, Categories: []string{"pubsub", "slow"},
+			Group: "pubsub", Summary: "Stops listening to messages posted to channels.",
+		},
+		CMD_PUBLISH: {
+			Handler: publish, Arity: 3, Flags: []string{"pubsub", "loading", "stale", "fast"}, Categories: []string{"pubsub", "fast"},
+			Channels: &ArgRange{1, 1, 1},
+			Group:    "pubsub", Summary: "Posts a message to a channel.",
+		},
+
+		// Key Commands
+		CMD_DEL: {
+			Handler: del, Arity: -2, Flags: []string{"write"}, Categories: []string{"keyspace", "write", "slow"}, Keys: writeKeys,
+			Group: "generic", Summary: "Deletes one or more keys.",
+		},
+		CMD_EXISTS: {
+			Handler: exists, Arity: -2, Flags: []string{"readonly", "fast"}, Categories: []string{"keyspace", "read", "fast"}, Keys: readKeys,
+			Group: "generic", Summary: "Determines whether one or more keys exist.",
+		},
+		CMD_KEYS: {
+			Handler: keys, Arity: 2, Flags: []string{"readonly"}, Categories: []string{"keyspace", "read", "slow", "dangerous"},
+			Group: "generic", Summary: "Returns all key names that match a pattern.",
+		},
+		CMD_RENAME: {
+			Handler: rename, Arity: 3, Flags: []string{"write"}, Categories: []string{"keyspace", "write", "slow"},
+			Keys: []KeySpec{
+				{ArgRange: ArgRange{1, 1, 1}, Read: true, Write: true},
+				{ArgRange: ArgRange{2, 2, 1}, Write: true},
+			},
+			Group: "generic", Summary: "Renames a key and overwrites the destination.",
+		},
+		CMD_SCAN: {
+			Handler: scan, Arity: -2, Flags: []string{"readonly"}, Categories: []string{"keyspace", "read", "slow"},
+			Group: "generic", Summary: "Iterates over the key names in the database.",
+		},
+		CMD_EXPIRE: {
+			Handler: expire, Arity: -3, Flags: []string{"write", "fast"}, Categories: []string{"keyspace", "write", "fast"}, Keys: writeKey,
+			Group: "generic", Summary: "Sets the expiration time of a key in seconds.",
+		},
+		CMD_PEXPIREAT: {
+			Handler: pexpireat, Arity: -3, Flags: []string{"write", "fast"}, Categories: []string{"keyspace", "write", "fast"}, Keys: writeKey,
+			Group: "generic", Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.",
+		},
+		CMD_TTL: {
+			Handler: ttl, Arity: 2, Flags: []string{"readonly", "fast"}, Categories: []string{"keyspace", "read", "fast"}, Keys: readKey,
+			Group: "generic", Summary: "Returns the expiration time in seconds of a key.",
+		},
+
+		// String Commands
+		CMD_SET: {
+			Handler: set, Arity: -3, Flags: []string{"write", "denyoom"}, Categories: []string{"write", "string", "slow"}, Keys: writeKey,
+			Group: "string", Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		},
+		CMD_GET: {
+			Handler: get, Arity: 2, Flags: []string{"readonly", "fast"}, Categories: []string{"read", "string", "fast"}, Keys: readKey,
+			Group: "string", Summary: "Returns the string value of a key.",
+		},
+		CMD_MSET: {
+			Handler: mset, Arity: -3, Flags: []string{"write", "denyoom"}, Categories: []string{"write", "string", "slow"},
+			Keys:  []KeySpec{{ArgRange: ArgRange{1, -1, 2}, Write: true}},
+			Group: "string", Summary: "Atomically creates or modifies the string values of one or more keys.",
+		},
+
+		// Server Commands
+		CMD_SAVE: {
+			Handler: save, Arity: 1, Flags: []string{"admin", "noscript"}, Categories: []string{"admin", "slow", "dangerous"},
+			Group: "server", Summary: "Synchronously saves the database(s) to disk.",
+		},
+		CMD_BGSAVE: {
+			Handler: bgsave, Arity: -1, Flags: []string{"admin", "noscript"}, Categories: []string{"admin", "slow", "dangerous"},
+			Group: "server", Summary: "Asynchronously saves the database(s) to disk.",
+		},
+		CMD_LASTSAVE: {
+			Handler: lastsave, Arity: 1, Flags: []string{"loading", "stale", "fast"}, Categories: []string{"admin", "fast", "dangerous"},
+			Group: "server", Summary: "Returns the Unix timestamp of the last successful save to disk.",
+		},
+		CMD_SHUTDOWN: {
+			Handler: shutdown, Arity: -1, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+			Group: "server", Summary: "Synchronously saves the database(s) to disk and shuts down the Redis server.",
+		},
+		CMD_INFO: {
+			Handler: info, Arity: -1, Flags: flagsLoading, Categories: []string{"slow", "dangerous"},
+			Group: "server", Summary: "Returns information and statistics about the server.",
+		},
+		CMD_FLUSHDB: {
+			Handler: flushdb, Arity: -1, Flags: []string{"write"}, Categories: []string{"keyspace", "write", "slow", "dangerous"},
+			Group: "server", Summary: "Removes all keys from the current database.",
+		},
+		CMD_DBSIZE: {
+			Handler: dbsize, Arity: 1, Flags: []string{"readonly", "fast"}, Categories: []string{"keyspace", "read", "fast"},
+			Group: "server", Summary: "Returns the number of keys in the database.",
+		},
+		CMD_CONFIG: {
+			Handler: configCmd, Arity: -2, Group: "server", Summary: "A container for server configuration commands.",
+			Subcommands: map[string]*CommandMeta{
+				"get": {
+					Arity: -3, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Returns the effective values of configuration parameters.",
+				},
+				"set": {
+					Arity: -4, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Sets configuration parameters in-flight.",
+				},
+				"rewrite": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Persists the effective configuration to file.",
+				},
+				"resetstat": {
+					Arity: 2, Flags: flagsAdmin, Categories: []string{"admin", "slow", "dangerous"},
+					Summary: "Resets the server's statistics.",
+				},
+			},
+		},
+	}
+	checkCommandTable()
+
+	acl.KnownCommand = func(name string) bool {
+		cmd, sub, hasSub := strings.Cut(name, "|")
+		meta, ok := commandTable[strings.ToUpper(cmd)]
+		if !ok || !hasSub {
+			return ok
+		}
+		_, ok = meta.Subcommands[sub]
+		return ok
+	}
+}
+
+// checkCommandTable panics if an entry could not be dispatched: every
+// command needs a handler and every command and subcommand an arity.
+func checkCommandTable() {
+	for name, meta := range commandTable {
+		if meta.Handler == nil || meta.Arity == 0 {
+			panic("commands: incomplete command table entry for " + name)
+		}
+		for sub, subMeta := range meta.Subcommands {
+			if subMeta.Handler != nil || subMeta.Arity == 0 {
+				panic("commands: incomplete command table entry for " + name + "|" + sub)
+			}
+		}
+	}
+}
+
+// lookupMeta returns the metadata for the command in v, or for its
+// subcommand when it has one, and the lowercase command and subcommand
+// names.
+func lookupMeta(v *resp.Value) (meta *CommandMeta, cmd, sub string) {
+	cmd = strings.ToLower(v.Array[0].String)
+	meta = commandTable[strings.ToUpper(cmd)]
+	if meta == nil || meta.Subcommands == nil || len(v.Array) < 2 {
+		return meta, cmd, ""
+	}
+	sub = strings.ToLower(v.Array[1].String)
+	if subMeta, ok := meta.Subcommands[sub]; ok {
+		return subMeta, cmd, sub
+	}
+	return meta, cmd, ""
+}
+
+// commandsInCategory lists the commands in category, in "cmd" or
+// "cmd|sub" form, for ACL CAT.
+func commandsInCategory(category string) []string {
+	var names []string
+	for name, meta := range commandTable {
+		if meta.Subcommands == nil {
+			if slices.Contains(meta.Categories, category) {
+				names = append(names, strings.ToLower(name))
+			}
+			continue
+		}
+		for sub, subMeta := range meta.Subcommands {
+			if slices.Contains(subMeta.Categories, category) {
+				names = append(names, strings.ToLower(name)+"|"+sub)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/pubsub"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// subscribedAllowed lists the commands a RESP2 client may send while it is
+// subscribed to a channel.
+var subscribedAllowed = map[string]bool{
+	CMD_SUBSCRIBE:   true,
+	CMD_UNSUBSCRIBE: true,
+	CMD_PING:        true,
+	CMD_QUIT:        true,
+}
+
+// subscribe replies with one confirmation per channel; all but the last are
+// written directly so that the handler still returns a single reply.
+func subscribe(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+
+	var reply *resp.Value
+	for i, arg := range args {
+		n := state.PubSub.Subscribe(c, arg.String)
+		reply = pubsub.Message(c, "subscribe", arg.String, &resp.Value{Type: resp.Integer, Integer: int64(n)})
+		if i < len(args)-1 {
+			_ = c.Write(reply)
+		}
+	}
+	return reply
+}
+
+func unsubscribe(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	channels := make([]string, 0, len(value.Array)-1)
+	for _, arg := range value.Array[1:] {
+		channels = append(channels, arg.String)
+	}
+	if len(channels) == 0 {
+		channels = state.PubSub.Channels(c)
+	}
+
+	if len(channels) == 0 {
+		reply := pubsub.Message(c, "unsubscribe", "", &resp.Value{Type: resp.Integer, Integer: 0})
+		reply.Array[1] = resp.NullBulk
+		return reply
+	}
+
+	var reply *resp.Value
+	for i, ch := range channels {
+		n := state.PubSub.Unsubscribe(c, ch)
+		reply = pubsub.Message(c, "unsubscribe", ch, &resp.Value{Type: resp.Integer, Integer: int64(n)})
+		if i < len(channels)-1 {
+			_ = c.Write(reply)
+		}
+	}
+	return reply
+}
+
+func publish(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	args := value.Array[1:]
+
+	n := state.PubSub.Publish(args[0].String, &resp.Value{Type: resp.BulkString, String: args[1].String})
+	return &resp.Value{Type: resp.Integer, Integer: int64(n)}
+}
@@ -1,63 +1,66 @@
 package commands
 
 import (
+	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
-func set(value *resp.Value, state *db.AppState) *resp.Value {
+func set(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
 	if len(args) != 2 {
-		return &resp.Value{
-			Type: resp.SimpleError, 
-			String: "ERR Invalid number of arguments for SET",
-		}
+		// No SET options are supported.
+		return &resp.Value{Type: resp.SimpleError, String: "ERR syntax error"}
 	}
 
 	key := args[0].String
 	val := args[1].String
 
 	db.DB.Set(key, val)
+	signalModifiedKey(c, state, key)
 
-	if state.Config.AOFenabled {
-		state.Aof.Writer.Write(value)
+	propagate(value, state, 1)
 
-		if state.Config.AOFfsync == "always" {
-			state.Aof.Writer.Flush()
-		}
-	}
+	return resp.OK
+}
+
+func get(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
+	key := value.Array[1].String
+	val, ok := lookupKeyRead(c, state, key)
 
-	if len(state.Config.RDB) > 0 {
-		db.IncrRDBTrackers()
+	if !ok {
+		return resp.NullBulk
 	}
 
 	return &resp.Value{
-		Type:   resp.SimpleString,
-		String: "OK",
+		Type:   resp.BulkString,
+		String: val.Value,
 	}
 }
 
-func get(value *resp.Value, state *db.AppState) *resp.Value {
+// mset sets every pair under one lock on the shards involved, so no client
+// sees some of the keys updated and others not.
+func mset(c *client.Client, value *resp.Value, state *db.AppState) *resp.Value {
 	args := value.Array[1:]
-	if len(args) != 1 {
-		return &resp.Value{
-			Type: resp.SimpleError, 
-			String: "ERR Invalid number of arguments for GET",
-		}
+	if len(args)%2 != 0 {
+		return &resp.Value{Type: resp.SimpleError, String: "ERR wrong number of arguments for 'mset' command"}
 	}
 
-	key := args[0].String
-	val, ok := db.DB.Get(key)
+	keys := make([]string, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, args[i].String)
+	}
 
-	if !ok {
-		return &resp.Value{
-			Type:   resp.Null,
-			IsNull: true,
+	db.DB.WithKeys(keys, func(tx db.Tx) {
+		for i := 0; i < len(args); i += 2 {
+			tx.Set(args[i].String, args[i+1].String)
 		}
+	})
+	for _, key := range keys {
+		signalModifiedKey(c, state, key)
 	}
 
-	return &resp.Value{
-		Type:   resp.BulkString,
-		String: val.Value,
-	}
+	propagate(value, state, len(keys))
+
+	return resp.OK
 }
@@ -4,41 +4,217 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/shivakuppa/Go_Redis/config"
 	myio "github.com/shivakuppa/Go_Redis/internals/io"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
+// Aof is the append only file. While it is off, Writer is nil and appended
+// commands are dropped; CONFIG SET appendonly turns it on and off.
 type Aof struct {
 	Writer *myio.RespWriter
 	File   *os.File
 	Config *config.Config
+	mu     sync.Mutex
+	stop   chan struct{}
+	fsync  config.FSyncMode
 }
 
+// NewAOF opens the append only file named by conf for appending. If that
+// fails the AOF stays off.
 func NewAOF(conf *config.Config) *Aof {
-	aof := Aof{Config: conf}
+	aof := newClosedAOF(conf)
+	if err := aof.open(); err != nil {
+		fmt.Println("Cannot open file: ", aof.path())
+	}
+	return aof
+}
+
+// newClosedAOF returns an AOF that is off until started.
+func newClosedAOF(conf *config.Config) *Aof {
+	return &Aof{Config: conf, fsync: conf.AOFfsync}
+}
 
-	fp := path.Join(conf.Dir, conf.AOFfn)
-	file, err := os.OpenFile(fp, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+func (aof *Aof) path() string {
+	return path.Join(aof.Config.Dir, aof.Config.AOFfn)
+}
+
+// open starts appending to the file and flushing it every second. The
+// caller must hold mu unless the AOF is not shared yet.
+func (aof *Aof) open() error {
+	file, err := os.OpenFile(aof.path(), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
 	if err != nil {
-		fmt.Println("Cannot open file: ", fp)
-		return &aof
+		return err
 	}
 
 	aof.Writer = myio.NewRespWriter(file)
 	aof.File = file
+	aof.stop = make(chan struct{})
 
-	go func() {
+	go func(stop chan struct{}) {
 		t := time.NewTicker(1 * time.Second)
 		defer t.Stop()
 
-		for range t.C {
-			if err := aof.Writer.Flush(); err != nil {
-				fmt.Println("AOF flush error:", err)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				if err := aof.Flush(); err != nil {
+					fmt.Println("AOF flush error:", err)
+				}
 			}
 		}
-	}()
+	}(aof.stop)
+	return nil
+}
+
+// Enabled reports whether commands are being appended.
+func (aof *Aof) Enabled() bool {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+	return aof.Writer != nil
+}
+
+// SetFsync changes when appended commands are flushed.
+func (aof *Aof) SetFsync(mode config.FSyncMode) {
+	aof.mu.Lock()
+	aof.fsync = mode
+	aof.mu.Unlock()
+}
+
+// Start turns the AOF on. The file is replaced by a fresh base holding
+// the commands that rebuild the keyspace, and commands are appended after
+// it. Commands appended while the base is written wait for it.
+func (aof *Aof) Start() error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.Writer != nil {
+		return nil
+	}
 
-	return &aof
+	fp := aof.path()
+	tmp := path.Join(aof.Config.Dir, fmt.Sprintf("temp-%d.aof", os.Getpid()))
+	if err := writeAOFBase(tmp, *DB.GetItems()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename aof: %w", err)
+	}
+	if err := syncDir(path.Dir(fp)); err != nil {
+		return fmt.Errorf("fsync aof dir: %w", err)
+	}
+	return aof.open()
+}
+
+// writeAOFBase writes the commands that rebuild items to a new file at fp.
+// Expiry is logged as PEXPIREAT, like the EXPIRE commands appended later.
+func writeAOFBase(fp string, items map[string]*Item) error {
+	file, err := os.OpenFile(fp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("open temp aof file: %w", err)
+	}
+	defer file.Close()
+
+	w := myio.NewRespWriter(file)
+	for key, item := range items {
+		if item.IsExpired() {
+			continue
+		}
+		if err := w.Write(command("SET", key, item.Value)); err != nil {
+			return fmt.Errorf("write aof: %w", err)
+		}
+		if item.hasExpiry() {
+			if err := w.Write(PExpireAtCommand(key, item.Expires)); err != nil {
+				return fmt.Errorf("write aof: %w", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("write aof: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("fsync aof: %w", err)
+	}
+	return file.Close()
+}
+
+// PExpireAtCommand is how an expiry is logged to the AOF. The time is
+// absolute, so replaying the file later does not extend the TTL by however
+// long the server was down.
+func PExpireAtCommand(key string, at time.Time) *resp.Value {
+	return command("PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10))
+}
+
+// command returns args as a RESP command array.
+func command(args ...string) *resp.Value {
+	v := &resp.Value{Type: resp.Array, Array: make([]*resp.Value, len(args))}
+	for i, arg := range args {
+		v.Array[i] = &resp.Value{Type: resp.BulkString, String: arg}
+	}
+	return v
+}
+
+// Append logs a write command. With appendfsync always it is flushed
+// before returning.
+func (aof *Aof) Append(value *resp.Value) error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.Writer == nil {
+		return nil
+	}
+	if err := aof.Writer.Write(value); err != nil {
+		return err
+	}
+	if aof.fsync == config.Always {
+		return aof.Writer.Flush()
+	}
+	return nil
+}
+
+func (aof *Aof) Flush() error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.Writer == nil {
+		return nil
+	}
+	return aof.Writer.Flush()
+}
+
+// Sync flushes buffered commands and fsyncs the file, whatever appendfsync
+// says.
+func (aof *Aof) Sync() error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.Writer == nil {
+		return nil
+	}
+	if err := aof.Writer.Flush(); err != nil {
+		return err
+	}
+	return aof.File.Sync()
+}
+
+// Close stops the background flush and closes the file, turning the AOF
+// off. Commands appended afterwards are dropped.
+func (aof *Aof) Close() error {
+	aof.mu.Lock()
+	defer aof.mu.Unlock()
+
+	if aof.Writer == nil {
+		return nil
+	}
+	close(aof.stop)
+	aof.Writer = nil
+	return aof.File.Close()
 }
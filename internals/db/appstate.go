@@ -1,24 +1,98 @@
 package db
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
 	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/pubsub"
+	"github.com/shivakuppa/Go_Redis/internals/tlsconf"
+	"github.com/shivakuppa/Go_Redis/internals/tracking"
 )
 
+// RedisVersion is the Redis version this server reports compatibility with.
+const RedisVersion = "7.2.0"
+
 type AppState struct {
-	Config 			*config.Config
-	Aof    			*Aof
-	BgSaveRunning  	bool
-	DBCopy			map[string]*Item
+	// Started is when the server started, and RunID identifies this run
+	// of it, as reported by INFO server.
+	Started time.Time
+	RunID   string
+
+	Config *config.Config
+	// ConfigMu guards the Config fields that CONFIG SET may change while
+	// connections read them.
+	ConfigMu  sync.RWMutex
+	Aof       *Aof
+	RDBStatus *RDBStatus
+	Loading   *LoadingStatus
+	Stats     *Stats
+	Saver     *SaveScheduler
+	Clients   *client.Registry
+	PubSub    *pubsub.Hub
+	Tracking  *tracking.Table
+	Shutdown  *Shutdown
+	// AuthLimiter throttles hosts that keep sending wrong passwords.
+	AuthLimiter *acl.Limiter
+	ACL         *acl.ACL
+	// TLS is the configuration of the TLS listener, if tls-port is set.
+	TLS *tlsconf.Context
 }
 
 func NewAppState(config *config.Config) *AppState {
 	state := AppState{
-		Config: config,
+		Started:   time.Now(),
+		RunID:     newRunID(),
+		Config:    config,
+		RDBStatus: NewRDBStatus(),
+		Loading:   NewLoadingStatus(),
+		Stats:     NewStats(),
+		Clients:   client.NewRegistry(),
+		PubSub:    pubsub.NewHub(),
+		Shutdown:  NewShutdown(),
+
+		AuthLimiter: acl.NewLimiter(),
+		ACL:         newACL(config),
+		TLS:         tlsconf.New(),
 	}
+	state.Tracking = tracking.NewTable(state.Clients, state.PubSub)
+
+	state.Saver = NewSaveScheduler(&state)
 
 	if config.AOFenabled {
 		state.Aof = NewAOF(config)
+	} else {
+		state.Aof = newClosedAOF(config)
 	}
 
 	return &state
 }
+
+// newACL sets up the users from the configuration: requirepass becomes the
+// default user's password, then the user directives are applied. Users
+// from an aclfile are loaded later, with the dataset.
+func newACL(config *config.Config) *acl.ACL {
+	a := acl.New()
+	a.Log.SetMaxLen(config.ACLLogMaxLen)
+	if config.Requirepass {
+		_ = a.SetUser(acl.DefaultUser, "resetpass", ">"+config.Password)
+	}
+	for _, u := range config.Users {
+		if err := a.SetUser(u[0], u[1:]...); err != nil {
+			log.Printf("invalid user %q in config: %v\n", u[0], err)
+		}
+	}
+	return a
+}
+
+// newRunID returns 40 random hex characters, like a Redis run_id.
+func newRunID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b) // never fails
+	return hex.EncodeToString(b)
+}
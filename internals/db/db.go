@@ -1,99 +1,485 @@
 package db
 
-import "sync"
+import (
+	"errors"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
+var ErrSnapshotInProgress = errors.New("snapshot already in progress")
+
+// DefaultShards is the number of shards NewDatabase splits the keyspace into.
+const DefaultShards = 64
+
+// Database is the keyspace, split into shards that each guard their part
+// with their own lock. A key's shard is derived from its hash slot, so keys
+// sharing a {hash tag} share a shard.
+//
+// Operations that span several shards (multi-key commands, snapshots, flushes)
+// lock the shards they need in ascending index order, which rules out
+// deadlocks between them.
 type Database struct {
-	store map[string]*Item
+	shards       []*shard
+	snapshotting atomic.Bool
+}
+
+// shard is one part of the keyspace. While a snapshot is being written,
+// store is frozen and read without locks by the saver; writes go to overlay
+// instead (a nil entry marks a deleted key) and are merged back when the
+// snapshot ends. Items reachable from store are never mutated in place,
+// which is what makes the frozen map a consistent point-in-time view.
+type shard struct {
+	store   map[string]*Item
+	overlay map[string]*Item
+	cleared bool // the shard was flushed while frozen
+	size    int
+	// slots indexes the live keys by hash slot, for SCAN.
+	slots map[int]map[string]struct{}
 	mu    sync.RWMutex
 }
 
 func NewDatabase() *Database {
-	return &Database{
+	return NewShardedDatabase(DefaultShards)
+}
+
+// NewShardedDatabase returns an empty keyspace split into n shards.
+func NewShardedDatabase(n int) *Database {
+	d := &Database{shards: make([]*shard, max(n, 1))}
+	for i := range d.shards {
+		d.shards[i] = newShard()
+	}
+	return d
+}
+
+func newShard() *shard {
+	return &shard{
 		store: map[string]*Item{},
-		mu:    sync.RWMutex{},
+		slots: map[int]map[string]struct{}{},
 	}
 }
 
 type DatabaseInterface interface {
 	Get(key string) (*Item, bool)
 	Set(key string, val string)
-	Del(key string)
-	GetKeys() []string
-	GetItems() map[string]*Item
-	GetLen() int 
+	Del(key string) bool
+	GetKeys() *[]string
+	GetItems() *map[string]*Item
+	GetLen() int
 	Reset()
 }
 
+func (d *Database) shardIndex(key string) int {
+	return KeySlot(key) % len(d.shards)
+}
+
+func (d *Database) shardFor(key string) *shard {
+	return d.shards[d.shardIndex(key)]
+}
+
+// lockAll write-locks every shard in order.
+func (d *Database) lockAll() {
+	for _, s := range d.shards {
+		s.mu.Lock()
+	}
+}
+
+func (d *Database) unlockAll() {
+	for _, s := range d.shards {
+		s.mu.Unlock()
+	}
+}
+
+// rlockAll read-locks every shard in order, for a consistent view of the
+// whole keyspace.
+func (d *Database) rlockAll() {
+	for _, s := range d.shards {
+		s.mu.RLock()
+	}
+}
+
+func (d *Database) runlockAll() {
+	for _, s := range d.shards {
+		s.mu.RUnlock()
+	}
+}
+
+// lookup returns the live item for key. s.mu must be held.
+func (s *shard) lookup(key string) (*Item, bool) {
+	if s.overlay != nil {
+		if item, ok := s.overlay[key]; ok {
+			return item, item != nil
+		}
+		if s.cleared {
+			return nil, false
+		}
+	}
+	item, ok := s.store[key]
+	return item, ok
+}
+
+// put stores item under key. s.mu must be held for writing.
+func (s *shard) put(key string, item *Item) {
+	if _, ok := s.lookup(key); !ok {
+		s.size++
+		slot := KeySlot(key)
+		keys, ok := s.slots[slot]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.slots[slot] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	if s.overlay != nil {
+		s.overlay[key] = item
+		return
+	}
+	s.store[key] = item
+}
+
+// remove deletes key. s.mu must be held for writing.
+func (s *shard) remove(key string) bool {
+	if _, ok := s.lookup(key); !ok {
+		return false
+	}
+	s.size--
+	slot := KeySlot(key)
+	delete(s.slots[slot], key)
+	if len(s.slots[slot]) == 0 {
+		delete(s.slots, slot)
+	}
+	if s.overlay != nil {
+		s.overlay[key] = nil
+		return true
+	}
+	delete(s.store, key)
+	return true
+}
+
+// reset empties the shard. s.mu must be held for writing.
+func (s *shard) reset() {
+	if s.overlay != nil {
+		s.overlay = map[string]*Item{}
+		s.cleared = true
+	} else {
+		s.store = map[string]*Item{}
+	}
+	s.slots = map[int]map[string]struct{}{}
+	s.size = 0
+}
+
+// forEach calls fn for every live key. s.mu must be held.
+func (s *shard) forEach(fn func(key string, item *Item)) {
+	if s.overlay == nil {
+		for k, v := range s.store {
+			fn(k, v)
+		}
+		return
+	}
+
+	if !s.cleared {
+		for k, v := range s.store {
+			if _, shadowed := s.overlay[k]; !shadowed {
+				fn(k, v)
+			}
+		}
+	}
+	for k, v := range s.overlay {
+		if v != nil {
+			fn(k, v)
+		}
+	}
+}
+
 func (d *Database) Get(key string) (*Item, bool) {
-	d.mu.RLock()
-	val, ok := d.store[key]
-	d.mu.RUnlock()
+	s := d.shardFor(key)
+	s.mu.RLock()
+	val, ok := s.lookup(key)
+	s.mu.RUnlock()
 	return val, ok
 }
 
 func (d *Database) Set(key string, value string) {
-	d.mu.Lock()
-	d.store[key] = makeItem(value)
-	d.mu.Unlock()
+	s := d.shardFor(key)
+	s.mu.Lock()
+	s.put(key, makeItem(value))
+	s.mu.Unlock()
+}
+
+// Restore stores an already built item, e.g. one read from a snapshot.
+func (d *Database) Restore(key string, item *Item) {
+	s := d.shardFor(key)
+	s.mu.Lock()
+	s.put(key, item)
+	s.mu.Unlock()
+}
+
+// SetExpiry sets the expiry of an existing key. The item is copied rather
+// than modified so a snapshot in progress keeps seeing the old value.
+func (d *Database) SetExpiry(key string, expires time.Time) bool {
+	s := d.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.lookup(key)
+	if !ok {
+		return false
+	}
+
+	updated := *item
+	updated.Expires = expires
+	s.put(key, &updated)
+	return true
 }
-func (d *Database) Del(key string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
 
-	delete(d.store, key)
+// Del deletes key and reports whether it existed.
+func (d *Database) Del(key string) bool {
+	s := d.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(key)
 }
 
+// GetKeys returns every key. All shards are locked together, so a
+// concurrent multi-key command such as RENAME is seen whole or not at all.
 func (d *Database) GetKeys() *[]string {
-    d.mu.RLock()
-    defer d.mu.RUnlock()
+	d.rlockAll()
+	defer d.runlockAll()
 
-    keys := make([]string, 0, len(d.store))
-    for k := range d.store {
-        keys = append(keys, k)
-    }
+	keys := make([]string, 0, d.lenLocked())
+	for _, s := range d.shards {
+		s.forEach(func(k string, _ *Item) {
+			keys = append(keys, k)
+		})
+	}
 
-    return &keys
+	return &keys
 }
 
+// GetItems returns a copy of every item, consistent across shards like
+// GetKeys.
 func (d *Database) GetItems() *map[string]*Item {
-    d.mu.RLock()
-    defer d.mu.RUnlock()
+	d.rlockAll()
+	defer d.runlockAll()
+
+	items := make(map[string]*Item, d.lenLocked())
+	for _, s := range d.shards {
+		s.forEach(func(k string, v *Item) {
+			copyItem := *v
+			items[k] = &copyItem
+		})
+	}
 
-    items := make(map[string]*Item, len(d.store))
-    for k, v := range d.store {
-        if v != nil {
-            copyItem := *v
-            items[k] = &copyItem
-        } else {
-            items[k] = nil
-        }
-    }
+	return &items
+}
+
+// ExpiresLen returns how many keys have a TTL.
+func (d *Database) ExpiresLen() int {
+	n := 0
+	for _, s := range d.shards {
+		s.mu.RLock()
+		s.forEach(func(_ string, v *Item) {
+			if v.hasExpiry() {
+				n++
+			}
+		})
+		s.mu.RUnlock()
+	}
+	return n
+}
 
-    return &items
+// lenLocked is GetLen for callers holding every shard lock.
+func (d *Database) lenLocked() int {
+	length := 0
+	for _, s := range d.shards {
+		length += s.size
+	}
+	return length
 }
 
 func (d *Database) GetLen() int {
-	d.mu.RLock()
-	length := len(d.store)
-	d.mu.RUnlock()
+	length := 0
+	for _, s := range d.shards {
+		s.mu.RLock()
+		length += s.size
+		s.mu.RUnlock()
+	}
 	return length
 }
 
+// Reset empties the keyspace. All shards are locked together so no reader
+// sees it half flushed.
 func (d *Database) Reset() {
-	d.mu.Lock()
-	d.store = map[string]*Item{}
-	d.mu.Unlock()
+	d.lockAll()
+	for _, s := range d.shards {
+		s.reset()
+	}
+	d.unlockAll()
 }
 
-func (db *Database) TryExpire(k string, i *Item) bool {
-	if i.shouldExpire() {
-		DB.mu.Lock()
-		DB.Del(k)
-		DB.mu.Unlock()
-		// state.generalStats.expired_keys++
-		return true
+// Tx gives access to the keys passed to WithKeys while their shards are
+// locked. Using it for any other key is a programming error.
+type Tx struct {
+	d *Database
+}
+
+func (tx Tx) Get(key string) (*Item, bool) {
+	return tx.d.shardFor(key).lookup(key)
+}
+
+func (tx Tx) Set(key string, value string) {
+	tx.d.shardFor(key).put(key, makeItem(value))
+}
+
+// Put stores item under key, e.g. one moved from another key.
+func (tx Tx) Put(key string, item *Item) {
+	tx.d.shardFor(key).put(key, item)
+}
+
+func (tx Tx) Del(key string) bool {
+	return tx.d.shardFor(key).remove(key)
+}
+
+// WithKeys runs fn with the shards holding keys write-locked, so that
+// multi-key commands such as MSET and RENAME are atomic. Shards are locked
+// in ascending index order.
+func (d *Database) WithKeys(keys []string, fn func(tx Tx)) {
+	idx := make([]int, 0, len(keys))
+	for _, k := range keys {
+		idx = append(idx, d.shardIndex(k))
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+
+	for _, i := range idx {
+		d.shards[i].mu.Lock()
+	}
+	defer func() {
+		for _, i := range slices.Backward(idx) {
+			d.shards[i].mu.Unlock()
+		}
+	}()
+
+	fn(Tx{d: d})
+}
+
+// Scan returns the keys of hash slots cursor, cursor+1, ... until at least
+// count keys were collected, and the cursor to continue from (0 once every
+// slot was visited). Since each key lives in a single slot and slots are
+// visited once and in order, a key present for the whole iteration is
+// returned exactly once, whatever else changes meanwhile.
+func (d *Database) Scan(cursor, count int) (int, []string) {
+	var keys []string
+	slot := cursor
+	for ; slot < SlotCount && len(keys) < count; slot++ {
+		s := d.shards[slot%len(d.shards)]
+		s.mu.RLock()
+		for k := range s.slots[slot] {
+			keys = append(keys, k)
+		}
+		s.mu.RUnlock()
+	}
+	if slot >= SlotCount {
+		slot = 0
+	}
+	return slot, keys
+}
+
+// Snapshot is a frozen, point-in-time view of the keyspace.
+type Snapshot struct {
+	stores []map[string]*Item
+}
+
+// Len returns the number of keys in the snapshot, expired ones included.
+func (sn *Snapshot) Len() int {
+	n := 0
+	for _, store := range sn.stores {
+		n += len(store)
+	}
+	return n
+}
+
+// Get returns the item key had when the snapshot was taken.
+func (sn *Snapshot) Get(key string) (*Item, bool) {
+	for _, store := range sn.stores {
+		if item, ok := store[key]; ok {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// All iterates over every key, shard by shard. The items must only be read.
+func (sn *Snapshot) All() iter.Seq2[string, *Item] {
+	return func(yield func(string, *Item) bool) {
+		for _, store := range sn.stores {
+			for k, v := range store {
+				if !yield(k, v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// BeginSnapshot freezes every shard at the same instant and returns the
+// frozen view. EndSnapshot must be called once the caller is done.
+func (d *Database) BeginSnapshot() (*Snapshot, error) {
+	if !d.snapshotting.CompareAndSwap(false, true) {
+		return nil, ErrSnapshotInProgress
+	}
+
+	d.lockAll()
+	defer d.unlockAll()
+
+	sn := &Snapshot{stores: make([]map[string]*Item, len(d.shards))}
+	for i, s := range d.shards {
+		s.overlay = map[string]*Item{}
+		sn.stores[i] = s.store
+	}
+	return sn, nil
+}
+
+// EndSnapshot merges the writes made during the snapshot back into the
+// shards. It costs O(keys written while frozen), not O(keyspace).
+func (d *Database) EndSnapshot() {
+	for _, s := range d.shards {
+		s.mu.Lock()
+		if s.cleared {
+			s.store = make(map[string]*Item, len(s.overlay))
+		}
+		for k, v := range s.overlay {
+			if v == nil {
+				delete(s.store, k)
+			} else {
+				s.store[k] = v
+			}
+		}
+		s.overlay = nil
+		s.cleared = false
+		s.mu.Unlock()
+	}
+	d.snapshotting.Store(false)
+}
+
+// TryExpire removes k if i, its item, has expired. expired reports whether
+// i has; removed whether this call removed it, rather than a concurrent one.
+func (d *Database) TryExpire(k string, i *Item) (expired, removed bool) {
+	if !i.shouldExpire() {
+		return false, false
+	}
+	s := d.shardFor(k)
+	s.mu.Lock()
+	// Only remove the key if it was not replaced since i was read.
+	if cur, ok := s.lookup(k); ok && cur == i {
+		s.remove(k)
+		removed = true
 	}
-	return false
+	s.mu.Unlock()
+	return true, removed
 }
 
 var DB = NewDatabase()
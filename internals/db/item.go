@@ -23,14 +23,24 @@ func makeItem(value string) *Item {
 	return item
 }
 
+func (item *Item) hasExpiry() bool {
+	return item.Expires.Unix() != UNIX_TS_EPOCH
+}
+
 func (item *Item) shouldExpire() bool {
 	return item.Expires.Unix() != UNIX_TS_EPOCH && time.Until(item.Expires).Seconds() <= 0
 }
 
+// IsExpired reports whether the item's TTL has passed. Expired items may
+// still be stored until something removes them.
+func (item *Item) IsExpired() bool {
+	return item.shouldExpire()
+}
+
 func (item *Item) approxMemUsage(name string) int64 {
 	stringHeader := 16
 	expHeader := 24
 	mapEntrySize := 32
 
 	return int64(stringHeader + len(name) + stringHeader + len(item.Value) + expHeader + mapEntrySize)
-}
\ No newline at end of file
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoadingStatus tracks the startup load of the AOF or RDB file so clients
+// can be answered with -LOADING and INFO can report progress.
+type LoadingStatus struct {
+	mu           sync.Mutex
+	loading      bool
+	source       string
+	start        time.Time
+	totalBytes   int64
+	loadedBytes  atomic.Int64
+	lastDuration time.Duration
+	keysLoaded   int64
+	keysExpired  int64
+}
+
+// LoadingSnapshot is a point-in-time copy of LoadingStatus.
+type LoadingSnapshot struct {
+	Loading      bool
+	Source       string
+	Start        time.Time
+	TotalBytes   int64
+	LoadedBytes  int64
+	LastDuration time.Duration
+	KeysLoaded   int64
+	KeysExpired  int64
+}
+
+func NewLoadingStatus() *LoadingStatus {
+	return &LoadingStatus{}
+}
+
+func (l *LoadingStatus) InProgress() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loading
+}
+
+// Start marks the beginning of a load of totalBytes from source ("rdb" or "aof").
+func (l *LoadingStatus) Start(source string, totalBytes int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.loading = true
+	l.source = source
+	l.start = time.Now()
+	l.totalBytes = totalBytes
+	l.loadedBytes.Store(0)
+}
+
+// Finish marks the load as done and records its results.
+func (l *LoadingStatus) Finish(keysLoaded, keysExpired int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.loading {
+		l.lastDuration = time.Since(l.start)
+	}
+	l.loading = false
+	l.keysLoaded = keysLoaded
+	l.keysExpired = keysExpired
+}
+
+// Reader wraps r so that bytes read from it count towards the progress.
+func (l *LoadingStatus) Reader(r io.Reader) io.Reader {
+	return &progressReader{r: r, loaded: &l.loadedBytes}
+}
+
+func (l *LoadingStatus) Snapshot() LoadingSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return LoadingSnapshot{
+		Loading:      l.loading,
+		Source:       l.source,
+		Start:        l.start,
+		TotalBytes:   l.totalBytes,
+		LoadedBytes:  l.loadedBytes.Load(),
+		LastDuration: l.lastDuration,
+		KeysLoaded:   l.keysLoaded,
+		KeysExpired:  l.keysExpired,
+	}
+}
+
+type progressReader struct {
+	r      io.Reader
+	loaded *atomic.Int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.loaded.Add(int64(n))
+	return n, err
+}
@@ -1,147 +1,398 @@
 package db
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/gob"
-	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/rdb"
 )
 
-type SnapshotTracker struct {
-	keys   int
-	ticker time.Ticker
-	rdb    *config.RDBSnapshot
-}
+var ErrSaveInProgress = errors.New("Background save already in progress")
 
-func NewSnapshotTracker(rdb *config.RDBSnapshot) *SnapshotTracker {
-	return &SnapshotTracker{
-		keys:   0,
-		ticker: *time.NewTicker(time.Second * time.Duration(rdb.Secs)),
-		rdb:    rdb,
-	}
-}
+// ErrUnsupportedType reports an RDB key of a type this server cannot store.
+var ErrUnsupportedType = errors.New("unsupported value type")
 
-var trackers = []*SnapshotTracker{}
+// ErrUnsupportedDB reports an RDB key outside db 0, the only database this
+// server has.
+var ErrUnsupportedDB = errors.New("unsupported database")
 
-func InitRDBTrackers(state *AppState) {
-	for _, rdb := range state.Config.RDB {
-		tracker := NewSnapshotTracker(&rdb)
-		trackers = append(trackers, tracker)
+// SaveRDB writes a snapshot to a temporary file, fsyncs it and atomically
+// renames it over dbfilename, so a crash mid-save never destroys the previous
+// snapshot. It fails if another save is running. The outcome is recorded in
+// state.RDBStatus.
+func SaveRDB(state *AppState) error {
+	started, _ := state.RDBStatus.tryStart(false)
+	if !started {
+		return ErrSaveInProgress
+	}
 
-		go func() {
-			defer tracker.ticker.Stop()
+	err := runSave(state)
+	if state.RDBStatus.next() {
+		go bgsaveLoop(state)
+	}
+	return err
+}
 
-			for range tracker.ticker.C {
-				// log.Printf("keys changed: %d - keys required to change: %d", tracker.keys, tracker.rdb.KeysChanged)
-				if tracker.keys >= tracker.rdb.KeysChanged {
-					SaveRDB(state)
-				}
-				tracker.keys = 0
-			}
-		}()
+// BgSaveRDB starts a snapshot in the background and returns immediately. If
+// a save is already running it fails, unless schedule is set, in which case
+// another save is queued to run right after the current one.
+func BgSaveRDB(state *AppState, schedule bool) (scheduled bool, err error) {
+	started, scheduled := state.RDBStatus.tryStart(schedule)
+	if scheduled {
+		return true, nil
+	}
+	if !started {
+		return false, ErrSaveInProgress
 	}
+
+	go bgsaveLoop(state)
+	return false, nil
 }
 
-func IncrRDBTrackers() {
-	for _, t := range trackers {
-		t.keys++
+func bgsaveLoop(state *AppState) {
+	for {
+		if err := runSave(state); err != nil {
+			log.Println("rdb - background save failed:", err)
+		}
+		if !state.RDBStatus.next() {
+			return
+		}
 	}
 }
 
-func SaveRDB(state *AppState) {
-	fp := path.Join(state.Config.Dir, state.Config.RDBfn)
-	file, err := os.OpenFile(fp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+func runSave(state *AppState) error {
+	start := time.Now()
+	state.RDBStatus.markStart(start)
+	err := saveRDB(state)
+	state.RDBStatus.record(start, err)
+	return err
+}
+
+func saveRDB(state *AppState) error {
+	log.Println("saving DB to RDB file")
+
+	// The snapshot is a point-in-time view: writes made while we serialize
+	// it land in the shards' overlays and are merged back by EndSnapshot.
+	snap, err := DB.BeginSnapshot()
 	if err != nil {
-		log.Println("error opening rdb file:", err)
-		return
+		return err
 	}
-	defer file.Close()
+	defer DB.EndSnapshot()
 
-	log.Println("saving DB to RDB file")
+	// CONFIG SET may change these while we save.
+	state.ConfigMu.RLock()
+	dir := state.Config.Dir
+	fp := path.Join(dir, state.Config.RDBfn)
+	opts := rdb.EncoderOptions{
+		Compress: state.Config.RDBcompression,
+		Checksum: state.Config.RDBchecksum,
+	}
+	state.ConfigMu.RUnlock()
 
-	var buf bytes.Buffer
-	var encodeErr error
+	// The temp file must sit next to fp for the rename to be atomic.
+	tmp := path.Join(dir, fmt.Sprintf("temp-%d.rdb", os.Getpid()))
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("open temp rdb file: %w", err)
+	}
 
-	if state.BgSaveRunning {
-		encodeErr = gob.NewEncoder(&buf).Encode(&state.DBCopy)
-	} else {
-		DB.mu.RLock()
-		encodeErr = gob.NewEncoder(&buf).Encode(DB.GetItems())
-		DB.mu.RUnlock()
+	if err := encodeRDB(file, snap, opts); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write rdb: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("fsync rdb: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("close rdb: %w", err)
 	}
 
-	if encodeErr != nil {
-		log.Println("error encoding db:", encodeErr)
-		return
+	if err := os.Rename(tmp, fp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename rdb: %w", err)
+	}
+	if err := syncDir(path.Dir(fp)); err != nil {
+		return fmt.Errorf("fsync rdb dir: %w", err)
 	}
 
-	data := buf.Bytes()
+	log.Println("saved RDB file successfully!")
+	return nil
+}
 
-	// Compute hash of buffer before writing
-	bsum, err := Hash(bytes.NewReader(data))
+// syncDir fsyncs a directory so a preceding rename is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
 	if err != nil {
-		log.Println("rdb - cannot compute buf checksum:", err)
-		return
+		return err
 	}
+	defer d.Close()
+	return d.Sync()
+}
 
-	// Write to file
-	if _, err := file.Write(data); err != nil {
-		log.Println("rdb - cannot write to file:", err)
-		return
+// encodeRDB writes the snapshot to w in the Redis RDB format.
+func encodeRDB(w io.Writer, snap *Snapshot, opts rdb.EncoderOptions) error {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	enc := rdb.NewEncoder(w, opts)
+	if err := enc.WriteHeader(); err != nil {
+		return err
 	}
-	if err := file.Sync(); err != nil {
-		log.Println("rdb - cannot flush file to disk:", err)
-		return
+
+	aux := [][2]string{
+		{"redis-ver", RedisVersion},
+		{"redis-bits", strconv.Itoa(strconv.IntSize)},
+		{"ctime", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"used-mem", strconv.FormatUint(mem.Alloc, 10)},
+		{"aof-base", "0"},
 	}
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		log.Println("rdb - cannot seek file:", err)
-		return
+	for _, kv := range aux {
+		if err := enc.WriteAux(kv[0], kv[1]); err != nil {
+			return err
+		}
 	}
 
-	fsum, err := Hash(file)
-	if err != nil {
-		log.Println("rdb - cannot compute file checksum:", err)
-		return
+	// RESIZEDB is a sizing hint for the loader, so it counts only the keys
+	// written below, not those that expired before the save.
+	keys, expires := 0, 0
+	for _, item := range snap.All() {
+		if item.shouldExpire() {
+			continue
+		}
+		keys++
+		if item.hasExpiry() {
+			expires++
+		}
 	}
 
-	if bsum != fsum {
-		log.Printf("rdb - buf and file checksums do not match:\nf=%s\nb=%s\n", fsum, bsum)
-		return
+	if err := enc.WriteSelectDB(0); err != nil {
+		return err
+	}
+	if err := enc.WriteResizeDB(keys, expires); err != nil {
+		return err
 	}
 
-	log.Println("saved RDB file successfully!")
-}
+	for k, item := range snap.All() {
+		if item.shouldExpire() {
+			continue
+		}
 
+		var expireAt time.Time
+		if item.hasExpiry() {
+			expireAt = item.Expires
+		}
+		if err := enc.WriteString(k, item.Value, expireAt); err != nil {
+			return err
+		}
+	}
+
+	return enc.WriteEOF()
+}
 
-func SyncRDB(state *AppState) {
+// SyncRDB loads dbfilename into the keyspace, reporting progress through
+// state.Loading. A missing file is not an error.
+func SyncRDB(state *AppState) error {
 	fp := path.Join(state.Config.Dir, state.Config.RDBfn)
-	file, err := os.OpenFile(fp, os.O_CREATE|os.O_RDONLY, 0644)
+	file, err := os.Open(fp)
 	if err != nil {
-		log.Println("error opening rdb file: ", err)
-		file.Close()
-		return
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("open rdb file: %w", err)
 	}
 	defer file.Close()
 
-	err = gob.NewDecoder(file).Decode(&DB.store)
+	var size int64
+	if fi, err := file.Stat(); err == nil {
+		size = fi.Size()
+	}
+
+	state.Loading.Start("rdb", size)
+	var loaded, expired int64
+	defer func() { state.Loading.Finish(loaded, expired) }()
+
+	// Keys are staged and only applied once the trailer checksum is verified,
+	// so a corrupt file never leaves a half-loaded keyspace behind.
+	items := map[string]*Item{}
+	dec := rdb.NewDecoder(state.Loading.Reader(file))
+	dec.MaxStringLen = uint64(state.Config.ProtoMaxBulkLen)
+	err = dec.Decode(func(e *rdb.Entry) error {
+		// Dropping keys this server cannot store would silently truncate
+		// a dump from Redis, so the load fails instead. The same goes for
+		// other databases, whose keys would overwrite db 0's.
+		if e.DB != 0 {
+			return fmt.Errorf("%w: key %q is in db %d", ErrUnsupportedDB, e.Key, e.DB)
+		}
+		if e.Type != rdb.TypeString {
+			return fmt.Errorf("%w: key %q has type %d", ErrUnsupportedType, e.Key, e.Type)
+		}
+
+		item := makeItem(e.Value)
+		if !e.ExpireAt.IsZero() {
+			item.Expires = e.ExpireAt
+			if item.shouldExpire() {
+				expired++
+				return nil
+			}
+		}
+
+		items[e.Key] = item
+		return nil
+	})
 	if err != nil {
-		log.Println("error decoding rdb file: ", err)
-		return
+		return fmt.Errorf("decode rdb file: %w", err)
+	}
+
+	if state.Config.RDBchecksum && dec.Checksum != 0 && dec.Checksum != dec.Computed {
+		return fmt.Errorf("wrong rdb checksum: expected %016x, got %016x", dec.Checksum, dec.Computed)
+	}
+
+	for k, item := range items {
+		DB.Restore(k, item)
+	}
+	loaded = int64(len(items))
+
+	log.Printf("synced RDB: %d keys loaded, %d expired keys discarded\n", loaded, expired)
+	return nil
+}
+
+// RDBStatus records snapshot activity for SAVE/BGSAVE, LASTSAVE and INFO.
+type RDBStatus struct {
+	mu           sync.Mutex
+	inProgress   bool
+	scheduled    bool
+	currentStart time.Time
+	lastTry      time.Time
+	lastSave     time.Time
+	lastOK       bool
+	lastDuration time.Duration
+	saves        int64
+
+	// dirty counts changes since the last successful save; dirtyAtStart is
+	// its value when the running save began.
+	dirty        atomic.Int64
+	dirtyAtStart int64
+
+	// idle is signalled whenever the save slot is released.
+	idle *sync.Cond
+}
+
+// RDBStatusSnapshot is a point-in-time copy of RDBStatus.
+type RDBStatusSnapshot struct {
+	InProgress   bool
+	Scheduled    bool
+	CurrentStart time.Time
+	LastTry      time.Time
+	LastSave     time.Time
+	LastOK       bool
+	LastDuration time.Duration
+	Saves        int64
+	Dirty        int64
+}
+
+func NewRDBStatus() *RDBStatus {
+	// At startup the dataset is considered saved, as in Redis.
+	s := &RDBStatus{lastSave: time.Now(), lastOK: true}
+	s.idle = sync.NewCond(&s.mu)
+	return s
+}
+
+// tryStart claims the single save slot. When the slot is taken and schedule
+// is set, a follow-up save is queued instead.
+func (s *RDBStatus) tryStart(schedule bool) (started, scheduled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.inProgress {
+		s.inProgress = true
+		return true, false
+	}
+	if schedule {
+		s.scheduled = true
+		return false, true
+	}
+	return false, false
+}
+
+// next is called when a save finishes. It keeps the slot and returns true
+// if a scheduled save must run next, otherwise it releases the slot.
+func (s *RDBStatus) next() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scheduled {
+		s.scheduled = false
+		return true
+	}
+	s.inProgress = false
+	s.idle.Broadcast()
+	return false
+}
+
+// WaitIdle blocks until no save is running or scheduled.
+func (s *RDBStatus) WaitIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.inProgress {
+		s.idle.Wait()
+	}
+}
+
+func (s *RDBStatus) markStart(start time.Time) {
+	s.mu.Lock()
+	s.currentStart = start
+	s.lastTry = start
+	s.dirtyAtStart = s.dirty.Load()
+	s.mu.Unlock()
+}
+
+func (s *RDBStatus) record(start time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.currentStart = time.Time{}
+	s.lastOK = err == nil
+	s.lastDuration = time.Since(start)
+	if err == nil {
+		s.lastSave = time.Now()
+		s.saves++
+		// Changes made while the snapshot was written are not in it.
+		s.dirty.Add(-s.dirtyAtStart)
 	}
-	log.Println("synced RDB")
 }
 
-func Hash(reader io.Reader) (string, error) {
-	hash := sha256.New()
-	if _, err := io.Copy(hash, reader); err != nil {
-		return "", err
+func (s *RDBStatus) Snapshot() RDBStatusSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return RDBStatusSnapshot{
+		InProgress:   s.inProgress,
+		Scheduled:    s.scheduled,
+		CurrentStart: s.currentStart,
+		LastTry:      s.lastTry,
+		LastSave:     s.lastSave,
+		LastOK:       s.lastOK,
+		LastDuration: s.lastDuration,
+		Saves:        s.saves,
+		Dirty:        s.dirty.Load(),
 	}
-	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// AddDirty records n changes to the dataset.
+func (s *RDBStatus) AddDirty(n int64) {
+	s.dirty.Add(n)
 }
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+)
+
+// bgsaveRetryDelay is how long to wait before retrying after a failed save.
+const bgsaveRetryDelay = 5 * time.Second
+
+// SaveScheduler triggers a background save as soon as any configured save
+// point is met: at least KeysChanged changes and at least Secs seconds since
+// the last successful save. It is driven by a single ticker and the global
+// dirty counter in RDBStatus, like the save logic in Redis' serverCron.
+type SaveScheduler struct {
+	mu    sync.Mutex
+	rules []config.RDBSnapshot
+	state *AppState
+	once  sync.Once
+	stop  chan struct{}
+}
+
+func NewSaveScheduler(state *AppState) *SaveScheduler {
+	return &SaveScheduler{
+		rules: slices.Clone(state.Config.RDB),
+		state: state,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Start launches the scheduler goroutine. Calling it again is a no-op.
+func (s *SaveScheduler) Start() {
+	s.once.Do(func() {
+		go s.run()
+	})
+}
+
+// Stop ends the scheduler goroutine.
+func (s *SaveScheduler) Stop() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
+
+// SetRules replaces the save points; an empty slice disables them.
+func (s *SaveScheduler) SetRules(rules []config.RDBSnapshot) {
+	s.mu.Lock()
+	s.rules = slices.Clone(rules)
+	s.mu.Unlock()
+}
+
+func (s *SaveScheduler) Rules() []config.RDBSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.rules)
+}
+
+func (s *SaveScheduler) run() {
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-t.C:
+			s.check(now)
+		}
+	}
+}
+
+func (s *SaveScheduler) check(now time.Time) {
+	status := s.state.RDBStatus.Snapshot()
+	if status.InProgress {
+		return
+	}
+	if !status.LastOK && now.Sub(status.LastTry) < bgsaveRetryDelay {
+		return
+	}
+
+	for _, rule := range s.Rules() {
+		if status.Dirty >= int64(rule.KeysChanged) &&
+			now.Sub(status.LastSave) >= time.Duration(rule.Secs)*time.Second {
+			log.Printf("%d changes in %d seconds. Saving...\n", rule.KeysChanged, rule.Secs)
+			BgSaveRDB(s.state, false)
+			return
+		}
+	}
+}
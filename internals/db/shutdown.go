@@ -0,0 +1,161 @@
+package db
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrShutdownFailed = errors.New("Errors trying to SHUTDOWN. Check logs.")
+	ErrNoShutdown     = errors.New("No shutdown in progress.")
+)
+
+// ShutdownOptions are the SHUTDOWN modifiers.
+type ShutdownOptions struct {
+	Save   bool // take a final snapshot even without save points
+	NoSave bool // skip the final snapshot
+	// Now skips waiting for lagging replicas. There are none, so it is
+	// accepted for compatibility only.
+	Now bool
+	// Force exits even if the AOF or the final snapshot cannot be written.
+	Force bool
+}
+
+// ShutdownRequest asks the server to stop. Whoever made the request
+// receives the outcome on Result: nil means the server is exiting.
+type ShutdownRequest struct {
+	Opts   ShutdownOptions
+	Result chan error
+}
+
+// Shutdown coordinates stopping the server with the commands still running.
+//
+// Every command runs between Enter and Exit. Freeze waits for the running
+// ones and holds back new ones, so the final save sees every command that
+// was started before it. Afterwards, either Thaw resumes service (the save
+// failed) or Close turns every later Enter away.
+type Shutdown struct {
+	gate     sync.RWMutex
+	closing  atomic.Bool
+	requests chan ShutdownRequest
+}
+
+func NewShutdown() *Shutdown {
+	return &Shutdown{requests: make(chan ShutdownRequest, 1)}
+}
+
+// Request queues a shutdown and returns the channel its outcome is sent on.
+// Only one request is served at a time; later ones wait their turn.
+func (s *Shutdown) Request(opts ShutdownOptions) <-chan error {
+	req := ShutdownRequest{Opts: opts, Result: make(chan error, 1)}
+	s.requests <- req
+	return req.Result
+}
+
+// Requests delivers queued shutdown requests to the server.
+func (s *Shutdown) Requests() <-chan ShutdownRequest {
+	return s.requests
+}
+
+// Abort cancels a request the server has not started on yet, as SHUTDOWN
+// ABORT does. The aborted requester gets ErrShutdownFailed.
+func (s *Shutdown) Abort() error {
+	select {
+	case req := <-s.requests:
+		req.Result <- ErrShutdownFailed
+		return nil
+	default:
+		return ErrNoShutdown
+	}
+}
+
+// Enter is called before running a command. It returns false once the
+// server is shutting down, in which case the command must not run.
+func (s *Shutdown) Enter() bool {
+	s.gate.RLock()
+	if s.closing.Load() {
+		s.gate.RUnlock()
+		return false
+	}
+	return true
+}
+
+// Exit is called when a command admitted by Enter is done.
+func (s *Shutdown) Exit() {
+	s.gate.RUnlock()
+}
+
+func (s *Shutdown) Freeze() {
+	s.gate.Lock()
+}
+
+func (s *Shutdown) Thaw() {
+	s.gate.Unlock()
+}
+
+// Close ends a Freeze for good: commands waiting in Enter are turned away.
+func (s *Shutdown) Close() {
+	s.closing.Store(true)
+	s.gate.Unlock()
+}
+
+// Closing reports whether the server is going away.
+func (s *Shutdown) Closing() bool {
+	return s.closing.Load()
+}
+
+// PrepareShutdown makes the dataset durable before exiting: the AOF is
+// flushed and fsynced and, when save points are configured or opts.Save is
+// set, a final snapshot is written. It must run between Freeze and Close.
+// Unless opts.Force is set, a failure cancels the shutdown.
+func PrepareShutdown(state *AppState, opts ShutdownOptions) error {
+	log.Println("User requested shutdown...")
+
+	if state.Aof != nil {
+		log.Println("Syncing the AOF before exiting.")
+		if err := state.Aof.Sync(); err != nil {
+			log.Println("Error syncing the AOF on shutdown:", err)
+			if !opts.Force {
+				return ErrShutdownFailed
+			}
+		}
+	}
+
+	// A partially loaded dataset must not overwrite the snapshot on disk.
+	save := opts.Save || len(state.Saver.Rules()) > 0
+	if opts.NoSave || state.Loading.InProgress() {
+		save = false
+	}
+	if save {
+		log.Println("Saving the final RDB snapshot before exiting.")
+		if err := finalSave(state); err != nil {
+			log.Println("Error trying to save the DB, can't exit:", err)
+			if !opts.Force {
+				return ErrShutdownFailed
+			}
+		}
+	}
+
+	state.Saver.Stop()
+	if state.Aof != nil {
+		if err := state.Aof.Close(); err != nil {
+			log.Println("Error closing the AOF:", err)
+		}
+	}
+	log.Println("Redis is now ready to exit, bye bye...")
+	return nil
+}
+
+// finalSave writes a snapshot once any background save has finished, so
+// that the file on disk ends up with the latest data.
+func finalSave(state *AppState) error {
+	for {
+		state.RDBStatus.WaitIdle()
+		err := SaveRDB(state)
+		if !errors.Is(err, ErrSaveInProgress) {
+			return err
+		}
+	}
+}
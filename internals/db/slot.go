@@ -0,0 +1,44 @@
+package db
+
+import "strings"
+
+// SlotCount is the number of hash slots keys are distributed over, as in
+// Redis Cluster.
+const SlotCount = 16384
+
+// KeySlot returns the hash slot of key: CRC16 of the key, or of its
+// "{hash tag}" if it has a non-empty one, modulo SlotCount. Keys sharing a
+// hash tag always land in the same slot and therefore the same shard.
+func KeySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key) & (SlotCount - 1))
+}
+
+// crc16Table holds CRC-16/XMODEM (polynomial 0x1021), the variant Redis
+// Cluster uses.
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+	return crc
+}
@@ -0,0 +1,85 @@
+package db
+
+import "sync/atomic"
+
+// Stats holds the server counters reported by INFO stats. CONFIG
+// RESETSTAT clears them.
+type Stats struct {
+	connections    atomic.Int64
+	commands       atomic.Int64
+	keyspaceHits   atomic.Int64
+	keyspaceMisses atomic.Int64
+	expiredKeys    atomic.Int64
+	peakMemory     atomic.Int64
+}
+
+// StatsSnapshot is a point-in-time copy of Stats.
+type StatsSnapshot struct {
+	Connections    int64
+	Commands       int64
+	KeyspaceHits   int64
+	KeyspaceMisses int64
+	ExpiredKeys    int64
+}
+
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+// AddConnection counts an accepted connection.
+func (s *Stats) AddConnection() {
+	s.connections.Add(1)
+}
+
+// AddCommand counts a processed command.
+func (s *Stats) AddCommand() {
+	s.commands.Add(1)
+}
+
+// AddLookup counts a key read as a hit or a miss.
+func (s *Stats) AddLookup(hit bool) {
+	if hit {
+		s.keyspaceHits.Add(1)
+	} else {
+		s.keyspaceMisses.Add(1)
+	}
+}
+
+// AddExpired counts a key removed because its TTL passed.
+func (s *Stats) AddExpired() {
+	s.expiredKeys.Add(1)
+}
+
+// ObserveMemory records used as the memory in use and returns the peak
+// seen so far.
+func (s *Stats) ObserveMemory(used int64) int64 {
+	for {
+		peak := s.peakMemory.Load()
+		if used <= peak {
+			return peak
+		}
+		if s.peakMemory.CompareAndSwap(peak, used) {
+			return used
+		}
+	}
+}
+
+// Reset sets every counter back to zero.
+func (s *Stats) Reset() {
+	s.connections.Store(0)
+	s.commands.Store(0)
+	s.keyspaceHits.Store(0)
+	s.keyspaceMisses.Store(0)
+	s.expiredKeys.Store(0)
+	s.peakMemory.Store(0)
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Connections:    s.connections.Load(),
+		Commands:       s.commands.Load(),
+		KeyspaceHits:   s.keyspaceHits.Load(),
+		KeyspaceMisses: s.keyspaceMisses.Load(),
+		ExpiredKeys:    s.expiredKeys.Load(),
+	}
+}
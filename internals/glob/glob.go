@@ -0,0 +1,93 @@
+// Package glob matches strings against Redis glob-style patterns, as used
+// for key names, channels and ACL rules.
+package glob
+
+// Match reports whether s matches the glob pattern, with the syntax of
+// Redis' stringmatchlen: *, ?, [abc], [^abc], [a-z] and \ escapes. Unlike
+// filepath.Match, * also matches '/', which is common in key names.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var ok bool
+			ok, pattern = matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			s = s[1:]
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class at the start of
+// pattern, just after the '['. It returns the pattern after the class.
+func matchClass(pattern string, c byte) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+
+	found := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				found = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				found = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				found = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	// An unterminated class runs to the end of the pattern, as in Redis.
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+	return found != not, pattern
+}
@@ -16,13 +16,15 @@ func NewRespWriter(w io.Writer) *RespWriter {
 	return &RespWriter{writer: bufio.NewWriter(w)}
 }
 
+// Write encodes v straight into the free space of the write buffer, so
+// replies that fit are never copied through an intermediate string.
 func (w *RespWriter) Write(v *resp.Value) error {
-	reply, err := resp.Serialize(v)
+	buf, err := resp.AppendValue(w.writer.AvailableBuffer(), v)
 	if err != nil {
 		return fmt.Errorf("serialize value: %w", err)
 	}
 
-	if _, err := w.writer.Write([]byte(reply)); err != nil {
+	if _, err := w.writer.Write(buf); err != nil {
 		return fmt.Errorf("write to buffer: %w", err)
 	}
 
@@ -0,0 +1,139 @@
+// Package pubsub keeps track of channel subscriptions and delivers
+// published messages to subscribed clients.
+package pubsub
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+type Hub struct {
+	mu       sync.RWMutex
+	channels map[string]map[int64]*client.Client
+	clients  map[int64]map[string]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		channels: map[string]map[int64]*client.Client{},
+		clients:  map[int64]map[string]struct{}{},
+	}
+}
+
+// Subscribe adds channel to c's subscriptions and returns how many channels
+// c is now subscribed to.
+func (h *Hub) Subscribe(c *client.Client, channel string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.channels[channel]
+	if !ok {
+		subs = map[int64]*client.Client{}
+		h.channels[channel] = subs
+	}
+	subs[c.ID] = c
+
+	chans, ok := h.clients[c.ID]
+	if !ok {
+		chans = map[string]struct{}{}
+		h.clients[c.ID] = chans
+	}
+	chans[channel] = struct{}{}
+	return len(chans)
+}
+
+// Unsubscribe removes channel from c's subscriptions and returns how many
+// channels c is still subscribed to.
+func (h *Hub) Unsubscribe(c *client.Client, channel string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if subs, ok := h.channels[channel]; ok {
+		delete(subs, c.ID)
+		if len(subs) == 0 {
+			delete(h.channels, channel)
+		}
+	}
+
+	chans := h.clients[c.ID]
+	delete(chans, channel)
+	if len(chans) == 0 {
+		delete(h.clients, c.ID)
+	}
+	return len(chans)
+}
+
+// Channels returns the channels c is subscribed to, sorted.
+func (h *Hub) Channels(c *client.Client) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	chans := make([]string, 0, len(h.clients[c.ID]))
+	for ch := range h.clients[c.ID] {
+		chans = append(chans, ch)
+	}
+	sort.Strings(chans)
+	return chans
+}
+
+// Count returns how many channels c is subscribed to.
+func (h *Hub) Count(c *client.Client) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[c.ID])
+}
+
+// NumChannels returns how many channels have subscribers.
+func (h *Hub) NumChannels() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.channels)
+}
+
+// NumClients returns how many clients are subscribed to a channel.
+func (h *Hub) NumClients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// IsSubscribed reports whether c is subscribed to channel.
+func (h *Hub) IsSubscribed(c *client.Client, channel string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.clients[c.ID][channel]
+	return ok
+}
+
+// Publish sends payload to every subscriber of channel and returns the
+// number of clients that received it.
+func (h *Hub) Publish(channel string, payload *resp.Value) int {
+	h.mu.RLock()
+	subs := make([]*client.Client, 0, len(h.channels[channel]))
+	for _, c := range h.channels[channel] {
+		subs = append(subs, c)
+	}
+	h.mu.RUnlock()
+
+	for _, c := range subs {
+		_ = c.Push(Message(c, "message", channel, payload))
+	}
+	return len(subs)
+}
+
+// Message builds a pub/sub message for c: a push for RESP3 clients and a
+// plain array for RESP2 ones.
+func Message(c *client.Client, kind, channel string, payload *resp.Value) *resp.Value {
+	typ := resp.Array
+	if c.RESP3() {
+		typ = resp.Push
+	}
+	return &resp.Value{Type: typ, Array: []*resp.Value{
+		{Type: resp.BulkString, String: kind},
+		{Type: resp.BulkString, String: channel},
+		payload,
+	}}
+}
@@ -0,0 +1,18 @@
+package rdb
+
+import "hash/crc64"
+
+// Redis checksums RDB files with CRC-64/Jones: reflected polynomial
+// 0xad93d23594c935a9, zero initial value and no final xor.
+var jonesTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+// crc64Update extends crc with p. hash/crc64 inverts the value on entry and
+// exit, so the inversions are undone here to match the Redis variant.
+func crc64Update(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, jonesTable, p)
+}
+
+// Checksum returns the Redis CRC64 of p.
+func Checksum(p []byte) uint64 {
+	return crc64Update(0, p)
+}
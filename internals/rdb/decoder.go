@@ -0,0 +1,388 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// DefaultMaxStringLen is the default Decoder.MaxStringLen, matching the
+// default proto-max-bulk-len.
+const DefaultMaxStringLen = 512 * 1024 * 1024
+
+// readChunk bounds how much is allocated ahead of the data actually read, so
+// a corrupt length prefix in a short file fails without a huge allocation.
+const readChunk = 64 * 1024
+
+// Entry is a key read from an RDB stream. Value is only set for string keys;
+// other types are skipped by the decoder and reported with their Type so the
+// caller can decide what to do with them.
+type Entry struct {
+	DB       int
+	Key      string
+	Type     byte
+	Value    string
+	ExpireAt time.Time
+}
+
+// Decoder reads an RDB stream produced by this server or by Redis.
+type Decoder struct {
+	r       *bufio.Reader
+	crc     uint64
+	scratch [8]byte
+
+	// Version is the RDB version from the file header.
+	Version int
+	// Aux holds the auxiliary fields found in the file.
+	Aux map[string]string
+	// Checksum is the CRC64 stored in the trailer (0 when absent or disabled).
+	Checksum uint64
+	// Computed is the CRC64 of the bytes read before the trailer.
+	Computed uint64
+
+	// MaxStringLen rejects strings, compressed or not, longer than this.
+	MaxStringLen uint64
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), Aux: map[string]string{}, MaxStringLen: DefaultMaxStringLen}
+}
+
+func (d *Decoder) read(n int) ([]byte, error) {
+	buf := make([]byte, 0, min(n, readChunk))
+	for len(buf) < n {
+		m := min(n-len(buf), readChunk)
+		buf = slices.Grow(buf, m)
+		if _, err := io.ReadFull(d.r, buf[len(buf):len(buf)+m]); err != nil {
+			return nil, err
+		}
+		buf = buf[:len(buf)+m]
+	}
+	d.crc = crc64Update(d.crc, buf)
+	return buf, nil
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	d.scratch[0] = b
+	d.crc = crc64Update(d.crc, d.scratch[:1])
+	return b, nil
+}
+
+// Decode reads the whole stream and calls fn for every key.
+func (d *Decoder) Decode(fn func(*Entry) error) error {
+	header, err := d.read(len(magic) + 4)
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return ErrBadMagic
+	}
+	version, err := strconv.Atoi(string(header[len(magic):]))
+	if err != nil || version < 1 || version > MaxVersion {
+		return fmt.Errorf("%w: %q", ErrBadVersion, header[len(magic):])
+	}
+	d.Version = version
+
+	dbNum := 0
+	var expireAt time.Time
+
+	for {
+		op, err := d.readByte()
+		if err != nil {
+			return fmt.Errorf("read opcode: %w", err)
+		}
+
+		switch op {
+		case opEOF:
+			return d.readTrailer()
+
+		case opSelectDB:
+			n, _, err := d.readLength()
+			if err != nil {
+				return fmt.Errorf("read db number: %w", err)
+			}
+			dbNum = int(n)
+
+		case opResizeDB:
+			if _, _, err := d.readLength(); err != nil {
+				return fmt.Errorf("read db size: %w", err)
+			}
+			if _, _, err := d.readLength(); err != nil {
+				return fmt.Errorf("read expires size: %w", err)
+			}
+
+		case opAux:
+			key, err := d.readString()
+			if err != nil {
+				return fmt.Errorf("read aux key: %w", err)
+			}
+			val, err := d.readString()
+			if err != nil {
+				return fmt.Errorf("read aux value: %w", err)
+			}
+			d.Aux[key] = val
+
+		case opExpireTime:
+			b, err := d.read(4)
+			if err != nil {
+				return fmt.Errorf("read expire time: %w", err)
+			}
+			expireAt = time.Unix(int64(binary.LittleEndian.Uint32(b)), 0)
+
+		case opExpireTimeMs:
+			b, err := d.read(8)
+			if err != nil {
+				return fmt.Errorf("read expire time ms: %w", err)
+			}
+			expireAt = time.UnixMilli(int64(binary.LittleEndian.Uint64(b)))
+
+		case opIdle:
+			if _, _, err := d.readLength(); err != nil {
+				return fmt.Errorf("read idle time: %w", err)
+			}
+
+		case opFreq:
+			if _, err := d.readByte(); err != nil {
+				return fmt.Errorf("read lfu freq: %w", err)
+			}
+
+		case opSlotInfo:
+			for range 3 {
+				if _, _, err := d.readLength(); err != nil {
+					return fmt.Errorf("read slot info: %w", err)
+				}
+			}
+
+		case opFunction2:
+			if _, err := d.readString(); err != nil {
+				return fmt.Errorf("read function: %w", err)
+			}
+
+		case opFunctionPre, opModuleAux:
+			return fmt.Errorf("%w: opcode 0x%X", ErrUnsupported, op)
+
+		default:
+			key, err := d.readString()
+			if err != nil {
+				return fmt.Errorf("read key: %w", err)
+			}
+
+			entry := &Entry{DB: dbNum, Key: key, Type: op, ExpireAt: expireAt}
+			if op == TypeString {
+				entry.Value, err = d.readString()
+			} else {
+				err = d.skipValue(op)
+			}
+			if err != nil {
+				return fmt.Errorf("read value of %q: %w", key, err)
+			}
+
+			expireAt = time.Time{}
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *Decoder) readTrailer() error {
+	d.Computed = d.crc
+	if d.Version < 5 {
+		return nil
+	}
+
+	b, err := d.read(8)
+	if err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+	d.Checksum = binary.LittleEndian.Uint64(b)
+	return nil
+}
+
+// readLength returns a length, or with encoded set, one of the enc* string
+// encodings.
+func (d *Decoder) readLength() (uint64, bool, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case len6Bit:
+		return uint64(b & 0x3F), false, nil
+	case len14Bit:
+		next, err := d.readByte()
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(b&0x3F)<<8 | uint64(next), false, nil
+	case lenEnc:
+		return uint64(b & 0x3F), true, nil
+	}
+
+	switch b {
+	case len32Bit:
+		buf, err := d.read(4)
+		if err != nil {
+			return 0, false, err
+		}
+		return uint64(binary.BigEndian.Uint32(buf)), false, nil
+	case len64Bit:
+		buf, err := d.read(8)
+		if err != nil {
+			return 0, false, err
+		}
+		return binary.BigEndian.Uint64(buf), false, nil
+	default:
+		return 0, false, fmt.Errorf("invalid length encoding 0x%X", b)
+	}
+}
+
+func (d *Decoder) readString() (string, error) {
+	n, encoded, err := d.readLength()
+	if err != nil {
+		return "", err
+	}
+
+	if !encoded {
+		if n > d.MaxStringLen {
+			return "", fmt.Errorf("string length %d too large", n)
+		}
+		buf, err := d.read(int(n))
+		return string(buf), err
+	}
+
+	switch n {
+	case encInt8:
+		b, err := d.read(1)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int8(b[0]))), nil
+	case encInt16:
+		b, err := d.read(2)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), nil
+	case encInt32:
+		b, err := d.read(4)
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), nil
+	case encLZF:
+		clen, _, err := d.readLength()
+		if err != nil {
+			return "", err
+		}
+		ulen, _, err := d.readLength()
+		if err != nil {
+			return "", err
+		}
+		if clen > d.MaxStringLen || ulen > d.MaxStringLen {
+			return "", fmt.Errorf("lzf length too large")
+		}
+		compressed, err := d.read(int(clen))
+		if err != nil {
+			return "", err
+		}
+		out, err := lzfDecompress(compressed, int(ulen))
+		return string(out), err
+	default:
+		return "", fmt.Errorf("invalid string encoding %d", n)
+	}
+}
+
+// skipDouble skips a RDB_TYPE_ZSET score: a length byte followed by ASCII,
+// with 253-255 reserved for nan, +inf and -inf.
+func (d *Decoder) skipDouble() error {
+	n, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	if n >= 253 {
+		return nil
+	}
+	_, err = d.read(int(n))
+	return err
+}
+
+func (d *Decoder) skipStrings(n uint64) error {
+	for range n {
+		if _, err := d.readString(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipValue consumes the payload of a non-string value.
+func (d *Decoder) skipValue(t byte) error {
+	switch t {
+	case TypeList, TypeSet, TypeListQuicklist:
+		n, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		return d.skipStrings(n)
+
+	case TypeHash:
+		n, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		return d.skipStrings(2 * n)
+
+	case TypeZSet, TypeZSet2:
+		n, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if _, err := d.readString(); err != nil {
+				return err
+			}
+			if t == TypeZSet2 {
+				_, err = d.read(8)
+			} else {
+				err = d.skipDouble()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case TypeListQuicklist2:
+		n, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if _, _, err := d.readLength(); err != nil {
+				return err
+			}
+			if _, err := d.readString(); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case TypeHashZipmap, TypeListZiplist, TypeSetIntset, TypeZSetZiplist,
+		TypeHashZiplist, TypeHashListpack, TypeZSetListpack, TypeSetListpack:
+		_, err := d.readString()
+		return err
+
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupported, t)
+	}
+}
@@ -0,0 +1,190 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// EncoderOptions mirror the rdbcompression and rdbchecksum directives.
+type EncoderOptions struct {
+	// Compress LZF-compresses strings longer than 20 bytes when that makes
+	// them smaller.
+	Compress bool
+	// Checksum writes the CRC64 trailer; when false the trailer is zero,
+	// which loaders treat as "not checksummed".
+	Checksum bool
+}
+
+// Encoder writes an RDB stream and keeps a running CRC64 of everything written.
+type Encoder struct {
+	w       *bufio.Writer
+	crc     uint64
+	opts    EncoderOptions
+	scratch [9]byte
+}
+
+func NewEncoder(w io.Writer, opts EncoderOptions) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (e *Encoder) write(p []byte) error {
+	e.crc = crc64Update(e.crc, p)
+	_, err := e.w.Write(p)
+	return err
+}
+
+func (e *Encoder) writeByte(b byte) error {
+	e.scratch[0] = b
+	return e.write(e.scratch[:1])
+}
+
+// WriteHeader writes the magic string and RDB version.
+func (e *Encoder) WriteHeader() error {
+	return e.write(fmt.Appendf(nil, "%s%04d", magic, Version))
+}
+
+// WriteAux writes an auxiliary field such as redis-ver or ctime.
+func (e *Encoder) WriteAux(key, value string) error {
+	if err := e.writeByte(opAux); err != nil {
+		return err
+	}
+	if err := e.writeString(key); err != nil {
+		return err
+	}
+	return e.writeString(value)
+}
+
+// WriteSelectDB starts the section for database n.
+func (e *Encoder) WriteSelectDB(n int) error {
+	if err := e.writeByte(opSelectDB); err != nil {
+		return err
+	}
+	return e.writeLength(uint64(n))
+}
+
+// WriteResizeDB records the number of keys and keys with an expiry in the
+// current database so the loader can presize its tables.
+func (e *Encoder) WriteResizeDB(size, expires int) error {
+	if err := e.writeByte(opResizeDB); err != nil {
+		return err
+	}
+	if err := e.writeLength(uint64(size)); err != nil {
+		return err
+	}
+	return e.writeLength(uint64(expires))
+}
+
+// WriteString writes a string key. A zero expireAt means the key never expires.
+func (e *Encoder) WriteString(key, value string, expireAt time.Time) error {
+	if !expireAt.IsZero() {
+		if err := e.writeByte(opExpireTimeMs); err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint64(e.scratch[:8], uint64(expireAt.UnixMilli()))
+		if err := e.write(e.scratch[:8]); err != nil {
+			return err
+		}
+	}
+
+	if err := e.writeByte(TypeString); err != nil {
+		return err
+	}
+	if err := e.writeString(key); err != nil {
+		return err
+	}
+	return e.writeString(value)
+}
+
+// WriteEOF terminates the stream with the EOF opcode and CRC64 trailer, then
+// flushes the underlying writer.
+func (e *Encoder) WriteEOF() error {
+	if err := e.writeByte(opEOF); err != nil {
+		return err
+	}
+
+	var crc uint64
+	if e.opts.Checksum {
+		crc = e.crc
+	}
+	binary.LittleEndian.PutUint64(e.scratch[:8], crc)
+	if _, err := e.w.Write(e.scratch[:8]); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *Encoder) writeLength(n uint64) error {
+	switch {
+	case n < 1<<6:
+		return e.writeByte(byte(n) | len6Bit<<6)
+	case n < 1<<14:
+		e.scratch[0] = byte(n>>8) | len14Bit<<6
+		e.scratch[1] = byte(n)
+		return e.write(e.scratch[:2])
+	case n <= 0xFFFFFFFF:
+		e.scratch[0] = len32Bit
+		binary.BigEndian.PutUint32(e.scratch[1:5], uint32(n))
+		return e.write(e.scratch[:5])
+	default:
+		e.scratch[0] = len64Bit
+		binary.BigEndian.PutUint64(e.scratch[1:9], n)
+		return e.write(e.scratch[:9])
+	}
+}
+
+func (e *Encoder) writeString(s string) error {
+	if ok, err := e.writeIntString(s); ok || err != nil {
+		return err
+	}
+
+	if e.opts.Compress && len(s) > 20 {
+		if c := lzfCompress([]byte(s)); c != nil && len(c) < len(s)-4 {
+			if err := e.writeByte(lenEnc<<6 | encLZF); err != nil {
+				return err
+			}
+			if err := e.writeLength(uint64(len(c))); err != nil {
+				return err
+			}
+			if err := e.writeLength(uint64(len(s))); err != nil {
+				return err
+			}
+			return e.write(c)
+		}
+	}
+
+	if err := e.writeLength(uint64(len(s))); err != nil {
+		return err
+	}
+	return e.write([]byte(s))
+}
+
+// writeIntString stores s as an 8, 16 or 32 bit integer when s is the
+// canonical decimal form of such a number.
+func (e *Encoder) writeIntString(s string) (bool, error) {
+	if len(s) == 0 || len(s) > 11 {
+		return false, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || strconv.FormatInt(v, 10) != s {
+		return false, nil
+	}
+
+	switch {
+	case v >= -1<<7 && v < 1<<7:
+		e.scratch[0] = lenEnc<<6 | encInt8
+		e.scratch[1] = byte(int8(v))
+		return true, e.write(e.scratch[:2])
+	case v >= -1<<15 && v < 1<<15:
+		e.scratch[0] = lenEnc<<6 | encInt16
+		binary.LittleEndian.PutUint16(e.scratch[1:3], uint16(int16(v)))
+		return true, e.write(e.scratch[:3])
+	default:
+		e.scratch[0] = lenEnc<<6 | encInt32
+		binary.LittleEndian.PutUint32(e.scratch[1:5], uint32(int32(v)))
+		return true, e.write(e.scratch[:5])
+	}
+}
@@ -0,0 +1,123 @@
+package rdb
+
+import "errors"
+
+const (
+	lzfHashLog    = 14
+	lzfMaxLiteral = 1 << 5
+	lzfMaxOffset  = 1 << 13
+	lzfMaxRef     = (1 << 8) + (1 << 3)
+)
+
+var errLZFCorrupt = errors.New("corrupt lzf data")
+
+func lzfHash(p []byte) uint32 {
+	v := uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+	return (v * 2654435761) >> (32 - lzfHashLog)
+}
+
+// lzfCompress encodes in using the LZF format understood by Redis. It returns
+// nil when the input is too small or does not compress.
+func lzfCompress(in []byte) []byte {
+	if len(in) < 4 {
+		return nil
+	}
+
+	out := make([]byte, 0, len(in))
+	var htab [1 << lzfHashLog]int32 // position+1 of the last occurrence, 0 if unseen
+
+	lit := 0
+	flushLiterals := func(end int) {
+		for lit < end {
+			n := min(end-lit, lzfMaxLiteral)
+			out = append(out, byte(n-1))
+			out = append(out, in[lit:lit+n]...)
+			lit += n
+		}
+	}
+
+	ip := 0
+	for ip+2 < len(in) {
+		h := lzfHash(in[ip:])
+		ref := int(htab[h]) - 1
+		htab[h] = int32(ip + 1)
+
+		if ref < 0 || ip-ref > lzfMaxOffset ||
+			in[ref] != in[ip] || in[ref+1] != in[ip+1] || in[ref+2] != in[ip+2] {
+			ip++
+			continue
+		}
+
+		n := 3
+		maxLen := min(len(in)-ip, lzfMaxRef)
+		for n < maxLen && in[ref+n] == in[ip+n] {
+			n++
+		}
+
+		flushLiterals(ip)
+		l := n - 2
+		off := ip - ref - 1
+		if l < 7 {
+			out = append(out, byte(l<<5|off>>8))
+		} else {
+			out = append(out, byte(7<<5|off>>8), byte(l-7))
+		}
+		out = append(out, byte(off))
+
+		ip += n
+		lit = ip
+	}
+	flushLiterals(len(in))
+
+	if len(out) >= len(in) {
+		return nil
+	}
+	return out
+}
+
+// lzfDecompress expands in, which must decode to exactly outLen bytes.
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+
+	for i := 0; i < len(in); {
+		ctrl := int(in[i])
+		i++
+
+		if ctrl < lzfMaxLiteral {
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > outLen {
+				return nil, errLZFCorrupt
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		l := ctrl >> 5
+		if l == 7 {
+			if i >= len(in) {
+				return nil, errLZFCorrupt
+			}
+			l += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, errLZFCorrupt
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - int(in[i]) - 1
+		i++
+
+		if ref < 0 || len(out)+l+2 > outLen {
+			return nil, errLZFCorrupt
+		}
+		// Byte-wise copy: the reference may overlap the bytes being written.
+		for j := 0; j < l+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != outLen {
+		return nil, errLZFCorrupt
+	}
+	return out, nil
+}
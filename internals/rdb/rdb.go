@@ -0,0 +1,77 @@
+// Package rdb reads and writes snapshots in the Redis RDB file format.
+package rdb
+
+import "errors"
+
+const (
+	// Version is the RDB version written by the encoder (Redis 7.2).
+	Version = 11
+	// MaxVersion is the newest RDB version the decoder accepts.
+	MaxVersion = 12
+
+	magic = "REDIS"
+)
+
+// Opcodes that can appear in place of a value type.
+const (
+	opSlotInfo     byte = 0xF4
+	opFunction2    byte = 0xF5
+	opFunctionPre  byte = 0xF6
+	opModuleAux    byte = 0xF7
+	opIdle         byte = 0xF8
+	opFreq         byte = 0xF9
+	opAux          byte = 0xFA
+	opResizeDB     byte = 0xFB
+	opExpireTimeMs byte = 0xFC
+	opExpireTime   byte = 0xFD
+	opSelectDB     byte = 0xFE
+	opEOF          byte = 0xFF
+)
+
+// Value types.
+const (
+	TypeString          byte = 0
+	TypeList            byte = 1
+	TypeSet             byte = 2
+	TypeZSet            byte = 3
+	TypeHash            byte = 4
+	TypeZSet2           byte = 5
+	TypeModulePreGA     byte = 6
+	TypeModule2         byte = 7
+	TypeHashZipmap      byte = 9
+	TypeListZiplist     byte = 10
+	TypeSetIntset       byte = 11
+	TypeZSetZiplist     byte = 12
+	TypeHashZiplist     byte = 13
+	TypeListQuicklist   byte = 14
+	TypeStreamListpacks byte = 15
+	TypeHashListpack    byte = 16
+	TypeZSetListpack    byte = 17
+	TypeListQuicklist2  byte = 18
+	TypeStreamListpack2 byte = 19
+	TypeSetListpack     byte = 20
+	TypeStreamListpack3 byte = 21
+)
+
+// Length encoding prefixes (two most significant bits of the first byte).
+const (
+	len6Bit  = 0
+	len14Bit = 1
+	len32Bit = 0x80
+	len64Bit = 0x81
+	lenEnc   = 3
+)
+
+// Special string encodings, used when the length prefix is lenEnc.
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+var (
+	ErrBadMagic    = errors.New("rdb: wrong signature")
+	ErrBadVersion  = errors.New("rdb: unsupported version")
+	ErrUnsupported = errors.New("rdb: unsupported value type")
+)
@@ -0,0 +1,97 @@
+package resp
+
+import "math"
+
+// Equal reports whether v and o hold the same RESP value. Aggregates are
+// compared element by element and in order, except sets, which only need the
+// same members.
+func (v *Value) Equal(o *Value) bool {
+	if v == o {
+		return true
+	}
+	if v == nil || o == nil || v.Type != o.Type || v.IsNull != o.IsNull {
+		return false
+	}
+
+	switch v.Type {
+	case Integer:
+		return v.Integer == o.Integer
+	case Boolean:
+		return v.Bool == o.Bool
+	case Double:
+		return v.Double == o.Double || (math.IsNaN(v.Double) && math.IsNaN(o.Double))
+	case Null:
+		return true
+	case Array, Push:
+		if len(v.Array) != len(o.Array) {
+			return false
+		}
+		for i := range v.Array {
+			if !v.Array[i].Equal(o.Array[i]) {
+				return false
+			}
+		}
+		return true
+	case Map, Attribute:
+		if len(v.Map) != len(o.Map) {
+			return false
+		}
+		for i := range v.Map {
+			if !v.Map[i].Key.Equal(o.Map[i].Key) || !v.Map[i].Value.Equal(o.Map[i].Value) {
+				return false
+			}
+		}
+		return true
+	case Set:
+		if len(v.Set) != len(o.Set) {
+			return false
+		}
+		for _, member := range v.Set {
+			if !o.Contains(member) {
+				return false
+			}
+		}
+		return true
+	default:
+		return v.String == o.String
+	}
+}
+
+// Contains reports whether the set v has a member equal to elem.
+func (v *Value) Contains(elem *Value) bool {
+	for _, member := range v.Set {
+		if member.Equal(elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMember adds elem to the set v unless an equal member is already
+// present, and reports whether it was added.
+func (v *Value) AddMember(elem *Value) bool {
+	if v.Contains(elem) {
+		return false
+	}
+	v.Set = append(v.Set, elem)
+	return true
+}
+
+// AddPair appends a bulk string key and its value to the map v.
+func (v *Value) AddPair(key string, val *Value) {
+	v.Map = append(v.Map, MapEntry{Key: &Value{Type: BulkString, String: key}, Value: val})
+}
+
+// Lookup returns the value of the first pair whose key is a string equal to
+// key.
+func (v *Value) Lookup(key string) (*Value, bool) {
+	for _, entry := range v.Map {
+		switch entry.Key.Type {
+		case SimpleString, BulkString, VerbatimString:
+			if entry.Key.String == key {
+				return entry.Value, true
+			}
+		}
+	}
+	return nil, false
+}
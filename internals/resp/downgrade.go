@@ -0,0 +1,124 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+)
+
+// ToRESP2 returns v as a RESP2 client would see it. Handlers build one
+// logical reply and the connection writer downgrades it when the client has
+// not switched to RESP3 with HELLO:
+//
+//   - maps become flat key/value arrays, sets and pushes become arrays
+//   - doubles and big numbers become bulk strings
+//   - booleans become the integers 1 and 0
+//   - nulls become null bulk strings, bulk errors become simple errors
+//   - verbatim strings lose their format prefix
+//   - attributes are dropped
+//
+// Values that are already valid RESP2 are returned without copying.
+func ToRESP2(v *Value) *Value {
+	switch v.Type {
+	case SimpleString, SimpleError, Integer, BulkString:
+		return v
+
+	case Array:
+		elems, changed := elementsToRESP2(v.Array)
+		if !changed {
+			return v
+		}
+		return &Value{Type: Array, Array: elems}
+
+	case Null:
+		return &Value{Type: BulkString, IsNull: true}
+
+	case Boolean:
+		if v.Bool {
+			return &Value{Type: Integer, Integer: 1}
+		}
+		return &Value{Type: Integer, Integer: 0}
+
+	case Double:
+		return &Value{Type: BulkString, String: formatDouble(v.Double)}
+
+	case BigNumber:
+		return &Value{Type: BulkString, String: v.String}
+
+	case BulkError:
+		return &Value{Type: SimpleError, String: v.String}
+
+	case VerbatimString:
+		if v.IsNull {
+			return &Value{Type: BulkString, IsNull: true}
+		}
+		s := v.String
+		if len(s) > 4 && s[3] == ':' {
+			s = s[4:]
+		}
+		return &Value{Type: BulkString, String: s}
+
+	case Map, Attribute:
+		if v.IsNull {
+			return &Value{Type: Array, IsNull: true}
+		}
+		flat := make([]*Value, 0, 2*len(v.Map))
+		for _, entry := range v.Map {
+			flat = append(flat, ToRESP2(entry.Key), ToRESP2(entry.Value))
+		}
+		return &Value{Type: Array, Array: flat}
+
+	case Set:
+		if v.IsNull {
+			return &Value{Type: Array, IsNull: true}
+		}
+		elems := make([]*Value, 0, len(v.Set))
+		for _, elem := range v.Set {
+			elems = append(elems, ToRESP2(elem))
+		}
+		return &Value{Type: Array, Array: elems}
+
+	case Push:
+		elems, _ := elementsToRESP2(v.Array)
+		return &Value{Type: Array, IsNull: v.IsNull, Array: elems}
+
+	default:
+		return v
+	}
+}
+
+// elementsToRESP2 downgrades each element, allocating a new slice only once
+// an element actually changes.
+func elementsToRESP2(elems []*Value) ([]*Value, bool) {
+	var out []*Value
+	for i, elem := range elems {
+		conv := elem
+		if elem.Type != Attribute {
+			conv = ToRESP2(elem)
+		}
+		if out == nil && (conv != elem || elem.Type == Attribute) {
+			out = make([]*Value, i, len(elems))
+			copy(out, elems[:i])
+		}
+		if out != nil && elem.Type != Attribute {
+			out = append(out, conv)
+		}
+	}
+	if out == nil {
+		return elems, false
+	}
+	return out, true
+}
+
+// formatDouble renders f the way the RESP3 double type does.
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,93 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// minValueLen is the shortest encoding of any value: a type byte and '\n'.
+const minValueLen = 2
+
+// FrameLen returns the length of the first complete request at the start of
+// buf, 0 if more bytes are needed, or -1 if buf is already known to be
+// invalid (parsing it then reports why). It only scans headers, not values.
+//
+// When more bytes are needed, need is a lower bound on the length buf must
+// reach for the request to be complete, such as the end of a bulk string
+// whose header has been read. Event loops should not call FrameLen again
+// before then, so a large request arriving in many reads is not rescanned
+// from its start after each one.
+func FrameLen(buf []byte, limits Limits) (n, need int) {
+	if len(buf) == 0 {
+		return 0, 1
+	}
+	if RESPDataType(buf[0]) != Array {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			if len(buf) > maxInlineSize {
+				return -1, 0
+			}
+			return 0, len(buf) + 1
+		}
+		return i + 1, 0
+	}
+	return frameEnd(buf, 0, limits, 0)
+}
+
+// frameEnd returns the offset just past the value starting at off, with the
+// same conventions as FrameLen.
+func frameEnd(buf []byte, off int, limits Limits, depth int) (end, need int) {
+	if off >= len(buf) {
+		return 0, off + minValueLen
+	}
+
+	i := bytes.IndexByte(buf[off:], '\n')
+	if i < 0 {
+		if len(buf)-off > maxInlineSize {
+			return -1, 0
+		}
+		return 0, len(buf) + 1
+	}
+	line := bytes.TrimSuffix(buf[off+1:off+i], []byte{'\r'})
+	next := off + i + 1
+
+	switch RESPDataType(buf[off]) {
+	case BulkString, BulkError, VerbatimString:
+		n, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil || n < -1 || n > limits.MaxBulkLen {
+			return -1, 0
+		}
+		if n < 0 {
+			return next, 0
+		}
+		end := int64(next) + n + 2
+		if end <= int64(len(buf)) {
+			return int(end), 0
+		}
+		return 0, int(end)
+
+	case Array, Set, Push, Map, Attribute:
+		n, err := strconv.ParseInt(string(line), 10, 64)
+		if err != nil || n < -1 || n > limits.MaxMultibulkLen || depth+1 > limits.MaxDepth {
+			return -1, 0
+		}
+		if t := RESPDataType(buf[off]); t == Map || t == Attribute {
+			n *= 2
+		}
+		for ; n > 0; n-- {
+			end, need := frameEnd(buf, next, limits, depth+1)
+			if end == 0 {
+				// The elements after this one take at least minValueLen each.
+				return 0, need + int(n-1)*minValueLen
+			}
+			if end < 0 {
+				return end, 0
+			}
+			next = end
+		}
+		return next, 0
+
+	default:
+		return next, 0
+	}
+}
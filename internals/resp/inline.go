@@ -0,0 +1,73 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shivakuppa/Go_Redis/internals/splitargs"
+)
+
+// maxInlineSize bounds a single inline request line, as in Redis.
+const maxInlineSize = 64 * 1024
+
+// ProtocolError reports a malformed request. The connection that sent it
+// cannot be resynchronised and should be closed after replying.
+type ProtocolError struct {
+	Msg string
+}
+
+func (e *ProtocolError) Error() string {
+	return "Protocol error: " + e.Msg
+}
+
+// ReadCommand reads the next client request. Requests starting with '*' are
+// RESP arrays; anything else is parsed with the inline grammar used by
+// telnet-style clients ("SET key \"a b\"\r\n"). Empty inline lines are
+// skipped.
+func (r *Reader) ReadCommand() (*Value, error) {
+	for {
+		first, err := r.br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("read resp first byte: %w", err)
+		}
+		if RESPDataType(first[0]) == Array {
+			return r.ReadValue()
+		}
+
+		value, err := readInline(r.br)
+		if err != nil || len(value.Array) > 0 {
+			return value, err
+		}
+	}
+}
+
+func readInline(reader *bufio.Reader) (*Value, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxInlineSize {
+			return nil, &ProtocolError{Msg: "too big inline request"}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read inline request: %w", err)
+		}
+		break
+	}
+
+	args, err := splitargs.Split(strings.TrimRight(string(line), "\r\n"))
+	if err != nil {
+		return nil, &ProtocolError{Msg: "unbalanced quotes in request"}
+	}
+
+	value := &Value{Type: Array, Array: make([]*Value, len(args))}
+	for i, arg := range args {
+		value.Array[i] = &Value{Type: BulkString, String: arg}
+	}
+	return value, nil
+}
@@ -38,8 +38,15 @@ type Value struct {
 	Integer int64
 	String  string
 	Array   []*Value
-	Map     map[string]*Value
-	Set     map[*Value]struct{}
+	Map     []MapEntry // Map and Attribute pairs, in wire order
+	Set     []*Value   // distinct members, in wire order
+}
+
+// MapEntry is one key/value pair of a Map or Attribute. Keys may be of any
+// type, e.g. the integer keys of some Redis replies.
+type MapEntry struct {
+	Key   *Value
+	Value *Value
 }
 
 func readUntilCRLF(reader *bufio.Reader) ([]byte, error) {
@@ -54,119 +61,195 @@ func readUntilCRLF(reader *bufio.Reader) ([]byte, error) {
 	return line[:len(line)-2], nil
 }
 
+// Limits bounds what a Reader will accept from its peer, so that a single
+// malformed or hostile request cannot make the server allocate unbounded
+// memory or recurse without end.
+type Limits struct {
+	MaxBulkLen      int64 // longest bulk string, in bytes
+	MaxMultibulkLen int64 // most elements in one aggregate
+	MaxDepth        int   // deepest nesting of aggregates
+}
+
+// DefaultLimits matches the Redis defaults for proto-max-bulk-len and
+// the multibulk length check.
+var DefaultLimits = Limits{
+	MaxBulkLen:      512 * 1024 * 1024,
+	MaxMultibulkLen: 1024 * 1024,
+	MaxDepth:        32,
+}
+
+// Reader parses successive RESP values from one buffered stream. A
+// connection must keep a single Reader for its lifetime: bytes pipelined
+// after the current command stay in its buffer for the next call.
+type Reader struct {
+	br     *bufio.Reader
+	limits Limits
+	depth  int
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{br: bufio.NewReader(r), limits: DefaultLimits}
+}
+
+// Reset discards any buffered data and makes r read from src.
+func (r *Reader) Reset(src io.Reader) {
+	r.br.Reset(src)
+	r.depth = 0
+}
+
+// SetLimits replaces the limits applied to subsequent reads.
+func (r *Reader) SetLimits(limits Limits) {
+	r.limits = limits
+}
+
+// ReadValue parses the next value from the stream. Values that exceed the
+// reader's limits are rejected with a *ProtocolError.
+func (r *Reader) ReadValue() (*Value, error) {
+	r.depth = 0
+	return r.readValue()
+}
+
+// Buffered returns the number of bytes already read from the stream but not
+// parsed yet. Zero means the current pipeline batch has been consumed.
+func (r *Reader) Buffered() int {
+	return r.br.Buffered()
+}
+
+// Deserialize parses a single value from reader using DefaultLimits. Unless
+// reader is already a *bufio.Reader, bytes following the value may be
+// buffered and lost, so long-lived streams should use a Reader instead.
 func Deserialize(reader io.Reader) (*Value, error) {
-	bufreader := bufio.NewReader(reader)
+	br, ok := reader.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(reader)
+	}
+	r := &Reader{br: br, limits: DefaultLimits}
+	return r.readValue()
+}
 
-	respType, err := bufreader.ReadByte()
+func (r *Reader) readValue() (*Value, error) {
+	respType, err := r.br.ReadByte()
 	if err != nil {
 		return nil, fmt.Errorf("read resp first byte: %w", err)
 	}
 
 	switch RESPDataType(respType) {
 	case SimpleString:
-		return deserializeSimpleString(bufreader)
+		return r.deserializeSimpleString()
 
 	case SimpleError:
-		return deserializeSimpleError(bufreader)
+		return r.deserializeSimpleError()
 
 	case Integer:
-		return deserializeInteger(bufreader)
+		return r.deserializeInteger()
 
 	case BulkString:
-		return deserializeBulkString(bufreader)
+		return r.deserializeBulkString()
 
 	case Array:
-		return deserializeArray(bufreader)
+		return r.deserializeArray()
 
 	// RESP3 types
 	case Null:
-		return deserializeNull(bufreader)
+		return r.deserializeNull()
 
 	case Boolean:
-		return deserializeBoolean(bufreader)
+		return r.deserializeBoolean()
 
 	case Double:
-		return deserializeDouble(bufreader)
+		return r.deserializeDouble()
 
 	case BigNumber:
-		return deserializeBigNumber(bufreader)
+		return r.deserializeBigNumber()
 
 	case BulkError:
-		return deserializeBulkError(bufreader)
+		return r.deserializeBulkError()
 
 	case VerbatimString:
-		return deserializeVerbatimString(bufreader)
+		return r.deserializeVerbatimString()
 
 	case Map:
-		return deserializeMap(bufreader)
+		return r.deserializeMap()
 
 	case Attribute:
-		return deserializeAttribute(bufreader)
+		return r.deserializeAttribute()
 
 	case Set:
-		return deserializeSet(bufreader)
+		return r.deserializeSet()
 
 	case Push:
-		return deserializePush(bufreader)
+		return r.deserializePush()
 
 	default:
-		return nil, fmt.Errorf("invalid RESP type: %c", respType)
+		return nil, fmt.Errorf("invalid resp type: %q", respType)
 	}
 }
 
+// Serialize returns the RESP encoding of value as a string. Writers should
+// prefer AppendValue, which avoids the intermediate string.
 func Serialize(value *Value) (string, error) {
+	buf, err := AppendValue(nil, value)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// AppendValue appends the RESP encoding of value to dst and returns the
+// extended buffer.
+func AppendValue(dst []byte, value *Value) ([]byte, error) {
 	if value == nil {
-		return "", fmt.Errorf("value is nil")
+		return dst, fmt.Errorf("value is nil")
 	}
 
 	switch value.Type {
 	case SimpleString:
-		return serializeSimpleString(value)
+		return appendSimpleString(dst, value), nil
 
 	case SimpleError:
-		return serializeSimpleError(value)
+		return appendSimpleError(dst, value), nil
 
 	case Integer:
-		return serializeInteger(value)
+		return appendInteger(dst, value), nil
 
 	case BulkString:
-		return serializeBulkString(value)
+		return appendBulkString(dst, value), nil
 
 	case Array:
-		return serializeArray(value)
+		return appendArray(dst, value)
 
 	// RESP3 types
 	case Null:
-		return serializeNull()
+		return appendNull(dst), nil
 
 	case Boolean:
-		return serializeBoolean(value)
+		return appendBoolean(dst, value), nil
 
 	case Double:
-		return serializeDouble(value)
+		return appendDouble(dst, value), nil
 
 	case BigNumber:
-		return serializeBigNumber(value)
+		return appendBigNumber(dst, value)
 
 	case BulkError:
-		return serializeBulkError(value)
+		return appendBulkError(dst, value), nil
 
 	case VerbatimString:
-		return serializeVerbatimString(value)
+		return appendVerbatimString(dst, value), nil
 
 	case Map:
-		return serializeMap(value)
+		return appendMap(dst, value)
 
 	case Attribute:
-		return serializeAttribute(value)
+		return appendAttribute(dst, value)
 
 	case Set:
-		return serializeSet(value)
+		return appendSet(dst, value)
 
 	case Push:
-		return serializePush(value)
+		return appendPush(dst, value)
 
 	default:
-		return "", fmt.Errorf("invalid RESP type: %c", value.Type)
+		return dst, fmt.Errorf("invalid resp type: %q", value.Type)
 	}
 }
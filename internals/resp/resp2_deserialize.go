@@ -1,14 +1,18 @@
 package resp
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"strconv"
 )
 
-func deserializeSimpleString(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+// preallocLimit caps how much is allocated up front from a client-supplied
+// length; anything larger grows as the data actually arrives.
+const preallocLimit = 64 * 1024
+
+func (r *Reader) deserializeSimpleString() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read simple string data: %w", err)
 	}
@@ -19,8 +23,8 @@ func deserializeSimpleString(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeSimpleError(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeSimpleError() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read simple error data: %w", err)
 	}
@@ -31,8 +35,8 @@ func deserializeSimpleError(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeInteger(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeInteger() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read integer data: %w", err)
 	}
@@ -48,19 +52,52 @@ func deserializeInteger(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeBulkString(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+// readBulkLength reads the length line of a bulk payload. -1 is only
+// returned when allowNull is set.
+func (r *Reader) readBulkLength(allowNull bool) (int64, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
-		return nil, fmt.Errorf("read bulk string data: %w", err)
+		return 0, fmt.Errorf("read bulk length: %w", err)
 	}
 
 	strLen, err := strconv.ParseInt(string(data), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("parse bulk string len: %w", err)
+	if err != nil || strLen < -1 || (strLen == -1 && !allowNull) || strLen > r.limits.MaxBulkLen {
+		return 0, &ProtocolError{Msg: "invalid bulk length"}
+	}
+	return strLen, nil
+}
+
+// readBulkPayload reads n bytes followed by CRLF.
+func (r *Reader) readBulkPayload(n int64) ([]byte, error) {
+	var payload []byte
+	if n <= preallocLimit {
+		payload = make([]byte, n)
+		if _, err := io.ReadFull(r.br, payload); err != nil {
+			return nil, fmt.Errorf("read bulk string: %w", err)
+		}
+	} else {
+		var buf bytes.Buffer
+		buf.Grow(preallocLimit)
+		if _, err := io.CopyN(&buf, r.br, n); err != nil {
+			return nil, fmt.Errorf("read bulk string: %w", err)
+		}
+		payload = buf.Bytes()
+	}
+
+	crlf := make([]byte, 2)
+	if _, err := io.ReadFull(r.br, crlf); err != nil {
+		return nil, fmt.Errorf("read bulk string terminator: %w", err)
 	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return nil, fmt.Errorf("bulk string not terminated correctly: %q", crlf)
+	}
+	return payload, nil
+}
 
-	if strLen < -1 {
-		return nil, fmt.Errorf("invalid bulk string len: %w", err)
+func (r *Reader) deserializeBulkString() (*Value, error) {
+	strLen, err := r.readBulkLength(true)
+	if err != nil {
+		return nil, err
 	}
 
 	if strLen == -1 {
@@ -70,20 +107,9 @@ func deserializeBulkString(reader *bufio.Reader) (*Value, error) {
 		}, nil
 	}
 
-	strBytes := make([]byte, strLen)
-	readLen, err := io.ReadFull(reader, strBytes)
+	strBytes, err := r.readBulkPayload(strLen)
 	if err != nil {
-		return nil, fmt.Errorf("read bulk string: %w", err)
-	}
-
-	if readLen != int(strLen) {
-		return nil, fmt.Errorf("wrong size of bulk string. expected: %d, got: %d", strLen, readLen)
-	}
-
-	crlf := make([]byte, 2)
-	n, err := io.ReadFull(reader, crlf)
-	if err != nil || n != 2 || crlf[0] != '\r' || crlf[1] != '\n' {
-		return nil, fmt.Errorf("bulk string not terminated correctly: %c", crlf)
+		return nil, err
 	}
 
 	return &Value{
@@ -92,19 +118,44 @@ func deserializeBulkString(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeArray(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+// readAggregateLength reads the element count of an aggregate type. perItem
+// is 2 for maps, whose count is in pairs.
+func (r *Reader) readAggregateLength(perItem int64) (int64, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
-		return nil, fmt.Errorf("read array data: %w", err)
+		return 0, fmt.Errorf("read aggregate length: %w", err)
 	}
 
-	numElements, err := strconv.ParseInt(string(data), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("parse number of array elements: %w", err)
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || n < -1 || n > r.limits.MaxMultibulkLen/perItem {
+		return 0, &ProtocolError{Msg: "invalid multibulk length"}
 	}
+	return n, nil
+}
 
-	if numElements < -1 {
-		return nil, fmt.Errorf("invalid number of array elements: %w", err)
+// readElements reads n nested values, enforcing the nesting depth limit.
+func (r *Reader) readElements(n int64, what string) ([]*Value, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > r.limits.MaxDepth {
+		return nil, &ProtocolError{Msg: "too many nested aggregates"}
+	}
+
+	elements := make([]*Value, 0, min(n, preallocLimit/8))
+	for i := range n {
+		element, err := r.readValue()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s element at index %d: %w", what, i, err)
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+func (r *Reader) deserializeArray() (*Value, error) {
+	numElements, err := r.readAggregateLength(1)
+	if err != nil {
+		return nil, err
 	}
 
 	if numElements == -1 {
@@ -114,13 +165,9 @@ func deserializeArray(reader *bufio.Reader) (*Value, error) {
 		}, nil
 	}
 
-	array := make([]*Value, numElements)
-	for i := range numElements {
-		element, err := Deserialize(reader)
-		if err != nil {
-			return nil, fmt.Errorf("error reading element at index %d: %w", i, err)
-		}
-		array[i] = element
+	array, err := r.readElements(numElements, "array")
+	if err != nil {
+		return nil, err
 	}
 
 	return &Value{
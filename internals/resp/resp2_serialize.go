@@ -5,38 +5,50 @@ import (
 	"strconv"
 )
 
-func serializeSimpleString(v *Value) (string, error) {
-	return "+" + v.String + "\r\n", nil
+func appendSimpleString(dst []byte, v *Value) []byte {
+	if v.String == "OK" {
+		return append(dst, okReply...)
+	}
+	dst = append(dst, byte(SimpleString))
+	dst = append(dst, v.String...)
+	return append(dst, crlf...)
 }
 
-func serializeSimpleError(v *Value) (string, error) {
-	return "-" + v.String + "\r\n", nil
+func appendSimpleError(dst []byte, v *Value) []byte {
+	dst = append(dst, byte(SimpleError))
+	dst = append(dst, v.String...)
+	return append(dst, crlf...)
 }
 
-func serializeInteger(v *Value) (string, error) {
-	return ":" + strconv.Itoa(int(v.Integer)) + "\r\n", nil
+func appendInteger(dst []byte, v *Value) []byte {
+	if v.Integer >= 0 && v.Integer < sharedHeaderCount {
+		return append(dst, integers[v.Integer]...)
+	}
+	dst = append(dst, byte(Integer))
+	dst = strconv.AppendInt(dst, v.Integer, 10)
+	return append(dst, crlf...)
 }
 
-func serializeBulkString(v *Value) (string, error) {
+func appendBulkString(dst []byte, v *Value) []byte {
 	if v.IsNull {
-		return "$-1\r\n", nil
+		return append(dst, nullBulkReply...)
 	}
-	return "$" + strconv.Itoa(len(v.String)) + "\r\n" + v.String + "\r\n", nil
+	dst = appendHeader(dst, byte(BulkString), len(v.String))
+	dst = append(dst, v.String...)
+	return append(dst, crlf...)
 }
 
-func serializeArray(v *Value) (string, error) {
+func appendArray(dst []byte, v *Value) ([]byte, error) {
 	if v.IsNull {
-		return "*-1\r\n", nil
+		return append(dst, nullArray...), nil
 	}
 
-	var serialized string
+	dst = appendHeader(dst, byte(Array), len(v.Array))
 	for _, elem := range v.Array {
-		s, err := Serialize(elem)
-		if err != nil {
-			return "", fmt.Errorf("serializing error element: %w", err)
+		var err error
+		if dst, err = AppendValue(dst, elem); err != nil {
+			return dst, fmt.Errorf("serializing error element: %w", err)
 		}
-		serialized += s
 	}
-
-	return "*" + strconv.Itoa(len(v.Array)) + "\r\n" + serialized, nil
+	return dst, nil
 }
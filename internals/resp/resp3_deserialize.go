@@ -1,22 +1,21 @@
 package resp
 
 import (
-	"bufio"
 	"fmt"
-	"io"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-func deserializeNull(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeNull() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read null: %w", err)
 	}
 
-	if string(data) != "_" {
-		return nil, fmt.Errorf("invalid null format: %w", err)
+	if len(data) != 0 {
+		return nil, fmt.Errorf("invalid null format: %q", data)
 	}
 
 	return &Value{
@@ -25,8 +24,8 @@ func deserializeNull(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeBoolean(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeBoolean() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read boolean: %w", err)
 	}
@@ -41,8 +40,8 @@ func deserializeBoolean(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeDouble(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeDouble() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read double value: %w", err)
 	}
@@ -68,8 +67,8 @@ func deserializeDouble(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeBigNumber(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeBigNumber() (*Value, error) {
+	data, err := readUntilCRLF(r.br)
 	if err != nil {
 		return nil, fmt.Errorf("read big number")
 	}
@@ -98,71 +97,57 @@ func deserializeBigNumber(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeBulkError(reader *bufio.Reader) (*Value, error) {
-	lengthBytes, err := readUntilCRLF(reader)
+func (r *Reader) deserializeBulkError() (*Value, error) {
+	length, err := r.readBulkLength(false)
 	if err != nil {
 		return nil, fmt.Errorf("read bulk error length: %w", err)
 	}
 
-	length, err := strconv.ParseInt(string(lengthBytes), 10, 64)
+	data, err := r.readBulkPayload(length)
 	if err != nil {
-		return nil, fmt.Errorf("parse bulk error length: %w", err)
-	}
-
-	data := make([]byte, length+2)
-	if _, err := io.ReadFull(reader, data); err != nil {
 		return nil, fmt.Errorf("read bulk error data: %w", err)
 	}
 
 	return &Value{
 		Type:   BulkError,
-		String: string(data[:length]),
+		String: string(data),
 	}, nil
 }
 
-func deserializeVerbatimString(reader *bufio.Reader) (*Value, error) {
-	lengthBytes, err := readUntilCRLF(reader)
+func (r *Reader) deserializeVerbatimString() (*Value, error) {
+	length, err := r.readBulkLength(false)
 	if err != nil {
 		return nil, fmt.Errorf("read verbatim length: %w", err)
 	}
 
-	length, err := strconv.Atoi(string(lengthBytes))
+	data, err := r.readBulkPayload(length)
 	if err != nil {
-		return nil, fmt.Errorf("parse verbatim length: %w", err)
-	}
-
-	data := make([]byte, length+2)
-	if _, err := io.ReadFull(reader, data); err != nil {
 		return nil, fmt.Errorf("read verbatim string: %w", err)
 	}
 
 	return &Value{
 		Type:   VerbatimString,
-		String: string(data[:length]),
+		String: string(data),
 	}, nil
 }
 
-func deserializeMap(reader *bufio.Reader) (*Value, error) {
-	countBytes, err := readUntilCRLF(reader)
+func (r *Reader) deserializeMap() (*Value, error) {
+	count, err := r.readAggregateLength(2)
 	if err != nil {
 		return nil, fmt.Errorf("read map length: %w", err)
 	}
-	count, err := strconv.Atoi(string(countBytes))
+	if count == -1 {
+		return &Value{Type: Map, IsNull: true}, nil
+	}
+
+	elements, err := r.readElements(2*count, "map")
 	if err != nil {
-		return nil, fmt.Errorf("parse map length: %w", err)
+		return nil, err
 	}
 
-	m := make(map[string]*Value, count)
-	for i := 0; i < count; i++ {
-		keyVal, err := Deserialize(reader)
-		if err != nil {
-			return nil, fmt.Errorf("read map key %d: %w", i, err)
-		}
-		valVal, err := Deserialize(reader)
-		if err != nil {
-			return nil, fmt.Errorf("read map value %d: %w", i, err)
-		}
-		m[keyVal.String] = valVal
+	m := make([]MapEntry, 0, len(elements)/2)
+	for i := 0; i < len(elements); i += 2 {
+		m = append(m, MapEntry{Key: elements[i], Value: elements[i+1]})
 	}
 
 	return &Value{
@@ -171,9 +156,9 @@ func deserializeMap(reader *bufio.Reader) (*Value, error) {
 	}, nil
 }
 
-func deserializeAttribute(reader *bufio.Reader) (*Value, error) {
+func (r *Reader) deserializeAttribute() (*Value, error) {
 	// Format is same as Map, just different type
-	v, err := deserializeMap(reader)
+	v, err := r.deserializeMap()
 	if err != nil {
 		return nil, fmt.Errorf("read attribute: %w", err)
 	}
@@ -181,17 +166,12 @@ func deserializeAttribute(reader *bufio.Reader) (*Value, error) {
 	return v, nil
 }
 
-func deserializeSet(reader *bufio.Reader) (*Value, error) {
-	data, err := readUntilCRLF(reader)
+func (r *Reader) deserializeSet() (*Value, error) {
+	numElements, err := r.readAggregateLength(1)
 	if err != nil {
 		return nil, fmt.Errorf("read set length: %w", err)
 	}
 
-	numElements, err := strconv.ParseInt(string(data), 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("parse set length: %w", err)
-	}
-
 	if numElements == -1 {
 		return &Value{
 			Type:   Set,
@@ -199,43 +179,73 @@ func deserializeSet(reader *bufio.Reader) (*Value, error) {
 		}, nil
 	}
 
-	if numElements < 0 {
-		return nil, fmt.Errorf("invalid set length: %d", numElements)
+	elements, err := r.readElements(numElements, "set")
+	if err != nil {
+		return nil, err
 	}
 
-	set := make(map[*Value]struct{}, numElements)
-	for i := int64(0); i < numElements; i++ {
-		elem, err := Deserialize(reader)
+	// Duplicate members are dropped, keeping the first. Most are detected by
+	// their encoding so that large sets are not compared pairwise; members
+	// that equal values can encode differently are compared with Equal, as
+	// AddMember does.
+	set := &Value{Type: Set, Set: make([]*Value, 0, len(elements))}
+	seen := make(map[string]struct{}, len(elements))
+	var loose []*Value
+	for _, elem := range elements {
+		enc, err := AppendValue(nil, elem)
 		if err != nil {
-			return nil, fmt.Errorf("error reading set element %d: %w", i, err)
+			return nil, fmt.Errorf("read set member: %w", err)
+		}
+		if _, dup := seen[string(enc)]; dup {
+			continue
 		}
-		set[elem] = struct{}{}
+		seen[string(enc)] = struct{}{}
+		if !canonical(elem) {
+			if slices.ContainsFunc(loose, elem.Equal) {
+				continue
+			}
+			loose = append(loose, elem)
+		}
+		set.Set = append(set.Set, elem)
 	}
 
-	return &Value{
-		Type: Set,
-		Set:  set,
-	}, nil
+	return set, nil
 }
 
-func deserializePush(reader *bufio.Reader) (*Value, error) {
-	countBytes, err := readUntilCRLF(reader)
-	if err != nil {
-		return nil, fmt.Errorf("read push length: %w", err)
+// canonical reports whether v's encoding is the only one of values equal
+// to it. Sets are unordered, and doubles such as 0 and -0 are equal.
+func canonical(v *Value) bool {
+	switch v.Type {
+	case Set, Double:
+		return false
+	case Array, Push:
+		for _, elem := range v.Array {
+			if !canonical(elem) {
+				return false
+			}
+		}
+	case Map, Attribute:
+		for _, entry := range v.Map {
+			if !canonical(entry.Key) || !canonical(entry.Value) {
+				return false
+			}
+		}
 	}
+	return true
+}
 
-	count, err := strconv.Atoi(string(countBytes))
+func (r *Reader) deserializePush() (*Value, error) {
+	count, err := r.readAggregateLength(1)
 	if err != nil {
-		return nil, fmt.Errorf("parse push length: %w", err)
+		return nil, fmt.Errorf("read push length: %w", err)
+	}
+	if count == -1 {
+		return &Value{Type: Push, IsNull: true}, nil
 	}
 
-	elements := make([]*Value, count)
-	for i := 0; i < count; i++ {
-		elem, err := Deserialize(reader)
-		if err != nil {
-			return nil, fmt.Errorf("read push element %d: %w", i, err)
-		}
-		elements[i] = elem
+	elements, err := r.readElements(count, "push")
+	if err != nil {
+		return nil, err
 	}
 
 	return &Value{
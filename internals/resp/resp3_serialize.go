@@ -7,170 +7,139 @@ import (
 	"strings"
 )
 
-func serializeNull() (string, error) {
-	return "_\r\n", nil
+func appendNull(dst []byte) []byte {
+	return append(dst, "_\r\n"...)
 }
 
-func serializeBoolean(v *Value) (string, error) {
+func appendBoolean(dst []byte, v *Value) []byte {
 	if v.Bool {
-		return "#t\r\n", nil
+		return append(dst, "#t\r\n"...)
 	}
-	return "#f\r\n", nil
+	return append(dst, "#f\r\n"...)
 }
 
-func serializeDouble(v *Value) (string, error) {
+func appendDouble(dst []byte, v *Value) []byte {
 	switch {
 	case math.IsInf(v.Double, 1):
-		return ",inf\r\n", nil
+		return append(dst, ",inf\r\n"...)
 	case math.IsInf(v.Double, -1):
-		return ",-inf\r\n", nil
+		return append(dst, ",-inf\r\n"...)
 	case math.IsNaN(v.Double):
-		return ",nan\r\n", nil
+		return append(dst, ",nan\r\n"...)
 	default:
-		return fmt.Sprintf(",%v\r\n", v.Double), nil
+		dst = append(dst, byte(Double))
+		dst = strconv.AppendFloat(dst, v.Double, 'g', -1, 64)
+		return append(dst, crlf...)
 	}
 }
 
-func serializeBigNumber(v *Value) (string, error) {
+func appendBigNumber(dst []byte, v *Value) ([]byte, error) {
 	data := v.String
 	if data == "" {
-		return "", fmt.Errorf("empty big number string")
+		return dst, fmt.Errorf("empty big number string")
 	}
 
 	start := 0
 	if data[0] == '+' || data[0] == '-' {
 		if len(data) == 1 {
-			return "", fmt.Errorf("big number cannot be only a sign")
+			return dst, fmt.Errorf("big number cannot be only a sign")
 		}
 		start = 1
 	}
 
 	for i := start; i < len(data); i++ {
 		if data[i] < '0' || data[i] > '9' {
-			return "", fmt.Errorf("invalid big number")
+			return dst, fmt.Errorf("invalid big number")
 		}
 	}
 
-	return "(" + data + "\r\n", nil
+	dst = append(dst, byte(BigNumber))
+	dst = append(dst, data...)
+	return append(dst, crlf...), nil
 }
 
-func serializeBulkError(v *Value) (string, error) {
+func appendBulkError(dst []byte, v *Value) []byte {
 	if v.IsNull {
-		return "!\r\n", nil
+		return append(dst, "!\r\n"...)
 	}
-	return "!" + strconv.Itoa(len(v.String)) + "\r\n" + v.String + "\r\n", nil
+	dst = appendHeader(dst, byte(BulkError), len(v.String))
+	dst = append(dst, v.String...)
+	return append(dst, crlf...)
 }
 
-func serializeVerbatimString(v *Value) (string, error) {
+func appendVerbatimString(dst []byte, v *Value) []byte {
 	// RESP3 verbatim strings use =<length>\r\n<format>:<data>\r\n
 	// format examples: "txt:", "mkd:", "html:"
 	if v.IsNull {
-		return "$-1\r\n", nil
+		return append(dst, nullBulkReply...)
 	}
 
-	formatPrefix := "txt:" // default format
+	prefix := ""
 	if !strings.Contains(v.String, ":") {
-		v.String = formatPrefix + v.String
+		prefix = "txt:" // default format
 	}
 
-	return "=" + strconv.Itoa(len(v.String)) + "\r\n" + v.String + "\r\n", nil
+	dst = appendHeader(dst, byte(VerbatimString), len(prefix)+len(v.String))
+	dst = append(dst, prefix...)
+	dst = append(dst, v.String...)
+	return append(dst, crlf...)
 }
 
-func serializeMap(v *Value) (string, error) {
+func appendMap(dst []byte, v *Value) ([]byte, error) {
 	if v.IsNull {
-		return "%-1\r\n", nil
+		return append(dst, "%-1\r\n"...), nil
 	}
-
-	var sb strings.Builder
-	sb.WriteString("%")
-	sb.WriteString(strconv.Itoa(len(v.Map)))
-	sb.WriteString("\r\n")
-
-	for key, val := range v.Map {
-		// Keys are always bulk strings in RESP3
-		keyVal := &Value{Type: BulkString, String: key}
-		keyStr, err := serializeBulkString(keyVal)
-		if err != nil {
-			return "", fmt.Errorf("serialize map key: %w", err)
-		}
-		sb.WriteString(keyStr)
-
-		valStr, err := Serialize(val)
-		if err != nil {
-			return "", fmt.Errorf("serialize map value: %w", err)
-		}
-		sb.WriteString(valStr)
-	}
-
-	return sb.String(), nil
+	return appendPairs(dst, byte(Map), v)
 }
 
-func serializeSet(v *Value) (string, error) {
+func appendAttribute(dst []byte, v *Value) ([]byte, error) {
 	if v.IsNull {
-		return "~-1\r\n", nil
+		return append(dst, "|-1\r\n"...), nil
 	}
+	return appendPairs(dst, byte(Attribute), v)
+}
 
-	var sb strings.Builder
-	sb.WriteString("~")
-	sb.WriteString(strconv.Itoa(len(v.Set)))
-	sb.WriteString("\r\n")
+func appendPairs(dst []byte, prefix byte, v *Value) ([]byte, error) {
+	dst = appendHeader(dst, prefix, len(v.Map))
 
-	for el := range v.Set {
-		s, err := Serialize(el)
-		if err != nil {
-			return "", fmt.Errorf("serialize set element: %w", err)
+	for _, entry := range v.Map {
+		var err error
+		if dst, err = AppendValue(dst, entry.Key); err != nil {
+			return dst, fmt.Errorf("serialize map key: %w", err)
+		}
+		if dst, err = AppendValue(dst, entry.Value); err != nil {
+			return dst, fmt.Errorf("serialize map value: %w", err)
 		}
-		sb.WriteString(s)
 	}
-
-	return sb.String(), nil
+	return dst, nil
 }
 
-func serializePush(v *Value) (string, error) {
+func appendSet(dst []byte, v *Value) ([]byte, error) {
 	if v.IsNull {
-		return ">-1\r\n", nil
+		return append(dst, "~-1\r\n"...), nil
 	}
 
-	var sb strings.Builder
-	sb.WriteString(">")
-	sb.WriteString(strconv.Itoa(len(v.Array)))
-	sb.WriteString("\r\n")
-
-	for _, el := range v.Array {
-		s, err := Serialize(el)
-		if err != nil {
-			return "", fmt.Errorf("serialize push element: %w", err)
+	dst = appendHeader(dst, byte(Set), len(v.Set))
+	for _, el := range v.Set {
+		var err error
+		if dst, err = AppendValue(dst, el); err != nil {
+			return dst, fmt.Errorf("serialize set element: %w", err)
 		}
-		sb.WriteString(s)
 	}
-
-	return sb.String(), nil
+	return dst, nil
 }
 
-func serializeAttribute(v *Value) (string, error) {
+func appendPush(dst []byte, v *Value) ([]byte, error) {
 	if v.IsNull {
-		return "|-1\r\n", nil
+		return append(dst, ">-1\r\n"...), nil
 	}
 
-	var sb strings.Builder
-	sb.WriteString("|")
-	sb.WriteString(strconv.Itoa(len(v.Map)))
-	sb.WriteString("\r\n")
-
-	for key, val := range v.Map {
-		keyVal := &Value{Type: BulkString, String: key}
-		keyStr, err := serializeBulkString(keyVal)
-		if err != nil {
-			return "", fmt.Errorf("serialize attribute key: %w", err)
-		}
-		sb.WriteString(keyStr)
-
-		valStr, err := Serialize(val)
-		if err != nil {
-			return "", fmt.Errorf("serialize attribute value: %w", err)
+	dst = appendHeader(dst, byte(Push), len(v.Array))
+	for _, el := range v.Array {
+		var err error
+		if dst, err = AppendValue(dst, el); err != nil {
+			return dst, fmt.Errorf("serialize push element: %w", err)
 		}
-		sb.WriteString(valStr)
 	}
-
-	return sb.String(), nil
+	return dst, nil
 }
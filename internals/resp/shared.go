@@ -0,0 +1,50 @@
+package resp
+
+import "strconv"
+
+// sharedHeaderCount is the number of precomputed length headers and small
+// integer replies, mirroring Redis' OBJ_SHARED_BULKHDR_LEN.
+const sharedHeaderCount = 1024
+
+var (
+	bulkHeaders  [sharedHeaderCount][]byte
+	arrayHeaders [sharedHeaderCount][]byte
+	integers     [sharedHeaderCount][]byte
+
+	okReply       = []byte("+OK\r\n")
+	nullBulkReply = []byte("$-1\r\n")
+	nullArray     = []byte("*-1\r\n")
+	crlf          = []byte("\r\n")
+)
+
+// Shared replies. Handlers may return these instead of allocating; they
+// must never be modified.
+var (
+	OK       = &Value{Type: SimpleString, String: "OK"}
+	NullBulk = &Value{Type: BulkString, IsNull: true}
+)
+
+func init() {
+	for i := range sharedHeaderCount {
+		n := strconv.Itoa(i)
+		bulkHeaders[i] = []byte("$" + n + "\r\n")
+		arrayHeaders[i] = []byte("*" + n + "\r\n")
+		integers[i] = []byte(":" + n + "\r\n")
+	}
+}
+
+// appendHeader appends "<prefix><n>\r\n", using a precomputed header when
+// one exists.
+func appendHeader(dst []byte, prefix byte, n int) []byte {
+	if n >= 0 && n < sharedHeaderCount {
+		switch prefix {
+		case byte(BulkString):
+			return append(dst, bulkHeaders[n]...)
+		case byte(Array):
+			return append(dst, arrayHeaders[n]...)
+		}
+	}
+	dst = append(dst, prefix)
+	dst = strconv.AppendInt(dst, int64(n), 10)
+	return append(dst, crlf...)
+}
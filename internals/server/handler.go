@@ -1,152 +1,162 @@
 package server
 
 import (
-	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/commands"
 	"github.com/shivakuppa/Go_Redis/internals/db"
-	myio "github.com/shivakuppa/Go_Redis/internals/io"
 	"github.com/shivakuppa/Go_Redis/internals/resp"
 )
 
 func (s *Server) handleConnection(c *client.Client, state *db.AppState) {
 	defer c.Conn.Close()
-	w := myio.NewRespWriter(c.Conn)
+	defer releaseClient(c, state)
+	c.Reader.SetLimits(readerLimits(state))
 
-	if state.Config.Requirepass {
-		log.Println(state.Config.Password)
-		authenticate(c, state, w)
+	if tc, ok := c.Conn.(*tls.Conn); ok {
+		if err := handshake(tc); err != nil {
+			slog.Info("TLS handshake failed", "addr", tc.RemoteAddr().String(), "error", err)
+			return
+		}
 	}
 
-	if state.Config.AOFenabled {
-		log.Println("syncing AOF records")
-		aofSync(state.Aof)
+	if deniedByProtectedMode(c, state) {
+		_ = c.Write(errProtectedMode)
+		_ = c.Flush()
+		return
 	}
 
-	if len(state.Config.RDB) > 0 {
-		db.SyncRDB(state)
-		db.InitRDBTrackers(state)
-	}
+	done := make(chan struct{})
+	defer close(done)
+	go writePushes(c, done)
 
 	for {
-		value, err := resp.Deserialize(c.Conn)
+		value, err := c.Reader.ReadCommand()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				fmt.Println("Client disconnected")
 				return
 			}
+			if state.Shutdown.Closing() {
+				return
+			}
 
 			fmt.Printf("Error deserializing request: %v\n", err)
-			errVal := &resp.Value{
-				Type:   resp.SimpleError,
-				String: "ERR invalid request",
-			}
-			_ = w.Write(errVal)
-			_ = w.Flush()
+			_ = c.Write(requestErrorReply(err))
+			_ = c.Flush()
+			return
+		}
+
+		// A nil reply means the command answers in some other way, as
+		// SHUTDOWN does by closing the connection.
+		if reply := commands.HandleCommand(c, value, state); reply != nil {
+			c.Write(reply)
+		}
+		if c.CloseAfterReply {
+			_ = c.Flush()
 			return
 		}
 
-		reply := commands.HandleCommand(c.Conn, value, state)
-		w.Write(reply)
-		w.Flush()
+		// Replies to a pipelined batch are flushed together once every
+		// command already received has been processed.
+		if c.Reader.Buffered() == 0 {
+			if err := c.Flush(); err != nil {
+				return
+			}
+		}
 	}
 }
 
-func aofSync(aof *db.Aof) {
-	file := aof.File
-	reader := bufio.NewReader(file)
-	replayState := db.NewAppState(aof.Config)
-	replayState.Config.AOFenabled = false
-
+// writePushes sends the pub/sub messages and invalidations queued for c
+// while its goroutine waits for the next request, until done is closed.
+func writePushes(c *client.Client, done <-chan struct{}) {
 	for {
-		value, err := resp.Deserialize(reader)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break // reached end of file
+		select {
+		case <-done:
+			return
+		case <-c.PushReady():
+			if err := c.FlushPushes(); err != nil {
+				_ = c.Close()
+				return
 			}
-			fmt.Println("Error reading AOF file:", err)
-			continue // skip bad entries instead of breaking everything
 		}
-
-		commands.ResolveCommand(value, replayState)
 	}
+}
 
-	replayState.Config.AOFenabled = true
-	fmt.Println("AOF replay complete — state restored successfully.")
+// errProtectedMode is the reply to clients refused by protected mode.
+var errProtectedMode = &resp.Value{
+	Type: resp.SimpleError,
+	String: "DENIED Redis is running in protected mode because protected mode is enabled and no password is set for the default user. " +
+		"In this mode connections are only accepted from the loopback interface. " +
+		"If you want to connect from external computers to Redis you may adopt one of the following solutions: " +
+		"1) Just disable protected mode sending the command 'CONFIG SET protected-mode no' from the loopback interface by connecting to Redis from the same host the server is running, however MAKE SURE Redis is not publicly accessible from internet if you do so. " +
+		"2) Alternatively you can just disable the protected mode by editing the Redis configuration file, and setting the protected mode option to 'no', and then restarting the server. " +
+		"3) If you started the server manually just for testing, restart it with the '--protected-mode no' option. " +
+		"4) Set up an authentication password for the default user. " +
+		"NOTE: You only need to do one of the above things in order for the server to start accepting connections from the outside.",
 }
 
-func authenticate(c *client.Client, state *db.AppState, w *myio.RespWriter) {
-	log.Println(state.Config.Password)
+// deniedByProtectedMode reports whether c must be refused: in protected
+// mode, while the default user needs no password, only local clients are
+// served.
+func deniedByProtectedMode(c *client.Client, state *db.AppState) bool {
+	state.ConfigMu.RLock()
+	protected := state.Config.ProtectedMode
+	state.ConfigMu.RUnlock()
+	return protected && !state.ACL.RequiresAuth() && !c.Local()
+}
 
-	value, err := resp.Deserialize(c.Conn)
-	if err != nil {
-		if errors.Is(err, io.EOF) {
-			fmt.Println("Client disconnected")
-			return
-		}
+// tlsHandshakeTimeout bounds how long a TLS client may take to finish
+// its handshake.
+const tlsHandshakeTimeout = 10 * time.Second
 
-		fmt.Printf("Error deserializing request: %v\n", err)
-		errVal := &resp.Value{
-			Type:   resp.SimpleError,
-			String: "ERR invalid request",
-		}
-		_ = w.Write(errVal)
-		_ = w.Flush()
-		return
+func handshake(tc *tls.Conn) error {
+	if err := tc.SetDeadline(time.Now().Add(tlsHandshakeTimeout)); err != nil {
+		return err
 	}
-
-	reply := commands.HandleCommand(c.Conn, value, state)
-	w.Write(reply)
-	w.Flush()
-
-	askPassword := &resp.Value{
-		Type:   resp.SimpleString,
-		String: "Please enter password:",
+	if err := tc.Handshake(); err != nil {
+		return err
 	}
+	return tc.SetDeadline(time.Time{})
+}
 
-	authenticatedMsg := &resp.Value{
-		Type:   resp.SimpleString,
-		String: "OK - Password authenticated",
+// readerLimits returns the request size limits from the configuration.
+func readerLimits(state *db.AppState) resp.Limits {
+	state.ConfigMu.RLock()
+	defer state.ConfigMu.RUnlock()
+	return resp.Limits{
+		MaxBulkLen:      state.Config.ProtoMaxBulkLen,
+		MaxMultibulkLen: state.Config.ProtoMaxMultibulkLen,
+		MaxDepth:        resp.DefaultLimits.MaxDepth,
 	}
+}
 
-	retryPassword := &resp.Value{
+// requestErrorReply is sent before closing a connection whose request could
+// not be parsed.
+func requestErrorReply(err error) *resp.Value {
+	errVal := &resp.Value{
 		Type:   resp.SimpleError,
-		String: "ERR invalid password, please try again",
+		String: "ERR invalid request",
 	}
+	var protoErr *resp.ProtocolError
+	if errors.As(err, &protoErr) {
+		errVal.String = "ERR " + protoErr.Error()
+	}
+	return errVal
+}
 
-	w.Write(askPassword)
-	w.Flush()
-
-	for {
-		// Wait for password input
-		reply, err := resp.Deserialize(c.Conn)
-		password := reply.Array[0].String
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				log.Println("Client disconnected during authentication")
-				return
-			}
-			log.Printf("Error while reading password: %v\n", err)
-			continue
-		}
-
-		log.Println(password)
-
-		if password == state.Config.Password {
-			c.Authenticated = true
-			w.Write(authenticatedMsg)
-			w.Flush()
-			log.Printf("Client %v authenticated successfully\n", c.Conn.RemoteAddr())
-			return
-		}
-
-		// Invalid password
-		w.Write(retryPassword)
-		w.Flush()
+// releaseClient drops everything the server keeps about a disconnected
+// client.
+func releaseClient(c *client.Client, state *db.AppState) {
+	state.Tracking.Disable(c)
+	for _, ch := range state.PubSub.Channels(c) {
+		state.PubSub.Unsubscribe(c, ch)
 	}
+	state.Clients.Remove(c)
 }
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+
+	"github.com/shivakuppa/Go_Redis/internals/commands"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// loadData restores the dataset once at startup: from the AOF when
+// appendonly is enabled, otherwise from the RDB snapshot. The save-point
+// scheduler only starts once the data is in memory.
+func loadData(state *db.AppState) error {
+	if state.Config.ACLFile != "" {
+		if err := state.ACL.LoadFile(state.Config.ACLFile); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if state.Config.AOFenabled {
+		log.Println("loading data from AOF")
+		err = loadAOF(state)
+	} else {
+		log.Println("loading data from RDB")
+		err = db.SyncRDB(state)
+	}
+
+	// Nothing to load (missing file) still has to end the loading phase.
+	if state.Loading.InProgress() {
+		state.Loading.Finish(0, 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	status := state.Loading.Snapshot()
+	log.Printf("DB loaded from disk: %.3f seconds\n", status.LastDuration.Seconds())
+
+	state.Saver.Start()
+	return nil
+}
+
+// loadAOF replays every command of the append only file. Replayed commands
+// run against a state without an AOF so they are not appended again, and
+// with their own RDBStatus so the replay does not count as unsaved changes.
+// A malformed command stops the load: the rest of the stream cannot be
+// parsed reliably. Only a command cut short by the end of the file, as a
+// crash mid-write leaves it, is tolerated.
+func loadAOF(state *db.AppState) error {
+	if state.Aof == nil || state.Aof.File == nil {
+		return nil
+	}
+
+	fi, err := state.Aof.File.Stat()
+	if err != nil {
+		return fmt.Errorf("stat aof file: %w", err)
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+
+	state.Loading.Start("aof", fi.Size())
+	section := io.NewSectionReader(state.Aof.File, 0, fi.Size())
+	reader := bufio.NewReader(state.Loading.Reader(section))
+
+	replayState := &db.AppState{
+		Config:    state.Config,
+		RDBStatus: db.NewRDBStatus(),
+		Loading:   db.NewLoadingStatus(),
+		Stats:     db.NewStats(),
+		Tracking:  state.Tracking,
+	}
+
+	replayed := 0
+	for {
+		if _, err := reader.Peek(1); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("read aof file: %w", err)
+		}
+
+		value, err := resp.Deserialize(reader)
+		if err != nil {
+			// The first byte was there, so running out of data here means
+			// the last command is truncated.
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				slog.Warn("AOF ends with a truncated command, ignoring it", "commands", replayed)
+				break
+			}
+			return fmt.Errorf("read aof file after %d commands: %w", replayed, err)
+		}
+
+		commands.ResolveCommand(value, replayState)
+		replayed++
+	}
+
+	state.Loading.Finish(int64(db.DB.GetLen()), 0)
+	log.Println("AOF replay complete — state restored successfully.")
+	return nil
+}
@@ -0,0 +1,551 @@
+//go:build linux
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/commands"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	myio "github.com/shivakuppa/Go_Redis/internals/io"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+const (
+	reactorReadSize  = 16 * 1024
+	reactorMaxEvents = 1024
+	// reactorKeepOut is the largest output buffer kept for reuse once it
+	// has been written out.
+	reactorKeepOut = 64 * 1024
+)
+
+// reactor serves every connection from a single epoll event loop. Commands
+// from all clients run one after another on the loop goroutine, so each one
+// is atomic with respect to every other, as in Redis. The keyspace locks stay
+// in place for background saves but are never contended by clients.
+//
+// With io-threads > 1, reading and parsing requests, and later writing
+// replies, are spread over helper goroutines while the loop waits for them;
+// command execution itself stays serial.
+//
+// A reactorConn is only touched on the loop goroutine. Commands running
+// elsewhere, such as those of TLS clients, reach its client through Push and
+// Close, which queue the work in posted and wake the loop up to do it.
+type reactor struct {
+	state   *db.AppState
+	threads int
+	epfd    int
+	wake    [2]int // pipe that interrupts epoll_wait when work is queued
+
+	mu       sync.Mutex
+	pending  []net.Conn
+	posted   []posted
+	stopping bool
+	done     chan struct{} // closed once the loop has returned
+
+	// Owned by the loop goroutine.
+	conns   map[int]*reactorConn
+	dirty   []*reactorConn // connections with output to write
+	scratch [][]byte       // one read buffer per I/O thread
+}
+
+// posted is work queued for the loop on behalf of another goroutine.
+type posted struct {
+	rc    *reactorConn
+	close bool // close the connection rather than write its pushes
+}
+
+type reactorConn struct {
+	r      *reactor
+	fd     int
+	conn   net.Conn
+	client *client.Client
+	limits resp.Limits
+
+	in      []byte // unparsed bytes of an incomplete request
+	need    int    // len(in) must reach this before the request can be complete
+	frame   bytes.Reader
+	cmds    []*resp.Value
+	readErr error // the request could not be parsed; reply and close
+	eof     bool
+
+	out     []byte
+	queued  bool // in r.dirty
+	writing bool // EPOLLOUT is armed
+	failed  bool // a write failed; close without replying
+	closing bool // close once out is written
+	closed  bool
+}
+
+// serveReactor serves the connections from every listener on one reactor,
+// and stops it once they are all closed.
+func (s *Server) serveReactor(state *db.AppState, listeners ...net.Listener) error {
+	r, err := newReactor(state)
+	if err != nil {
+		return err
+	}
+	go r.loop()
+
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.acceptFrom(l)
+		}()
+	}
+	wg.Wait()
+	r.stop()
+	return nil
+}
+
+// acceptFrom hands the connections from l to the loop until l is closed.
+func (r *reactor) acceptFrom(l net.Listener) {
+	failures := 0
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Error("Error during accepting", "error", err)
+			time.Sleep(acceptBackoff(failures))
+			failures++
+			continue
+		}
+		failures = 0
+		r.add(conn)
+	}
+}
+
+func newReactor(state *db.AppState) (*reactor, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reactor{
+		state:   state,
+		threads: max(state.Config.IOThreads, 1),
+		epfd:    epfd,
+		conns:   map[int]*reactorConn{},
+		done:    make(chan struct{}),
+	}
+	r.scratch = make([][]byte, r.threads)
+	for i := range r.scratch {
+		r.scratch[i] = make([]byte, reactorReadSize)
+	}
+
+	if err := syscall.Pipe2(r.wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		syscall.Close(epfd) //nolint:errcheck // already failing
+		return nil, err
+	}
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(r.wake[0])}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, r.wake[0], &ev); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// add hands an accepted connection to the loop. It may be called from any
+// goroutine.
+func (r *reactor) add(conn net.Conn) {
+	r.mu.Lock()
+	r.pending = append(r.pending, conn)
+	r.mu.Unlock()
+
+	r.wakeUp()
+}
+
+// stop makes the loop close every connection and return, and waits for it.
+func (r *reactor) stop() {
+	r.mu.Lock()
+	r.stopping = true
+	r.mu.Unlock()
+
+	r.wakeUp()
+	<-r.done
+}
+
+// post queues work on rc for the loop. It may be called from any goroutine.
+func (r *reactor) post(rc *reactorConn, close bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Once stopping, the loop may have closed the wake-up pipe already.
+	if r.stopping {
+		return
+	}
+	r.posted = append(r.posted, posted{rc: rc, close: close})
+	r.wakeUp()
+}
+
+func (r *reactor) wakeUp() {
+	// A full pipe means the loop has a wake-up pending already.
+	_, _ = syscall.Write(r.wake[1], []byte{0})
+}
+
+func (r *reactor) loop() {
+	defer close(r.done)
+	events := make([]syscall.EpollEvent, reactorMaxEvents)
+	var ready []*reactorConn
+
+	for {
+		n, err := syscall.EpollWait(r.epfd, events, -1)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			slog.Error("epoll_wait failed", "error", err)
+			return
+		}
+
+		ready = ready[:0]
+		for _, ev := range events[:n] {
+			fd := int(ev.Fd)
+			if fd == r.wake[0] {
+				if !r.adopt() {
+					r.closeAll()
+					return
+				}
+				continue
+			}
+			rc, ok := r.conns[fd]
+			if !ok {
+				continue
+			}
+			if ev.Events&(syscall.EPOLLIN|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+				ready = append(ready, rc)
+			}
+			if ev.Events&syscall.EPOLLOUT != 0 {
+				r.markDirty(rc)
+			}
+		}
+
+		r.fanOut(ready, (*reactorConn).readAndParse)
+		for _, rc := range ready {
+			r.execute(rc)
+		}
+
+		dirty := r.dirty
+		r.fanOut(dirty, func(rc *reactorConn, _ int) { rc.flush() })
+		for _, rc := range dirty {
+			rc.queued = false
+			if rc.failed || (rc.closing && len(rc.out) == 0) {
+				r.close(rc)
+			}
+		}
+		r.dirty = dirty[:0]
+	}
+}
+
+// adopt registers the connections queued by add and does the work queued
+// by post. It returns false once stop was called.
+func (r *reactor) adopt() bool {
+	var drain [64]byte
+	for {
+		if n, err := syscall.Read(r.wake[0], drain[:]); n <= 0 || err != nil {
+			break
+		}
+	}
+
+	r.mu.Lock()
+	pending, work, stopping := r.pending, r.posted, r.stopping
+	r.pending, r.posted = nil, nil
+	r.mu.Unlock()
+
+	if stopping {
+		for _, conn := range pending {
+			conn.Close() //nolint:errcheck // shutting down
+		}
+		return false
+	}
+
+	for _, conn := range pending {
+		fd, err := connFD(conn)
+		if err != nil {
+			slog.Error("Cannot serve connection from the event loop", "error", err)
+			conn.Close() //nolint:errcheck // dropping the connection
+			continue
+		}
+
+		rc := &reactorConn{r: r, fd: fd, conn: conn, limits: readerLimits(r.state)}
+		c := client.NewClient(conn)
+		c.Reader = resp.NewReader(&rc.frame)
+		c.Reader.SetLimits(rc.limits)
+		c.Writer = myio.NewRespWriter(rc)
+		c.SetCloser(func() error {
+			r.post(rc, true)
+			return nil
+		})
+		c.SetPushNotifier(func() { r.post(rc, false) })
+		rc.client = c
+
+		ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(r.epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			slog.Error("Cannot register connection", "error", err)
+			conn.Close() //nolint:errcheck // dropping the connection
+			continue
+		}
+		r.conns[fd] = rc
+		r.state.Clients.Add(c)
+		r.state.Stats.AddConnection()
+
+		if deniedByProtectedMode(c, r.state) {
+			_ = c.Write(errProtectedMode)
+			_ = c.Flush()
+			rc.closing = true
+			r.markDirty(rc)
+		}
+	}
+
+	for _, w := range work {
+		switch {
+		case w.rc.closed:
+		case w.close:
+			w.rc.closing = true
+			r.markDirty(w.rc)
+		default:
+			// The pushes land in rc.out, and mark it dirty.
+			_ = w.rc.client.FlushPushes()
+		}
+	}
+	return true
+}
+
+// closeAll writes out what it can of the pending replies, then closes every
+// connection and the loop's own descriptors.
+func (r *reactor) closeAll() {
+	for _, rc := range r.conns {
+		rc.flush()
+		r.close(rc)
+	}
+	syscall.Close(r.wake[0]) //nolint:errcheck // shutting down
+	syscall.Close(r.wake[1]) //nolint:errcheck // shutting down
+	syscall.Close(r.epfd)    //nolint:errcheck // shutting down
+}
+
+// connFD returns the descriptor behind conn. Go has already made it
+// non-blocking; the connection keeps ownership and closes it.
+func connFD(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return -1, errors.New("connection has no file descriptor")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+
+	fd := -1
+	if err := raw.Control(func(f uintptr) { fd = int(f) }); err != nil {
+		return -1, err
+	}
+	return fd, nil
+}
+
+// fanOut runs fn over conns, split across the I/O threads.
+func (r *reactor) fanOut(conns []*reactorConn, fn func(rc *reactorConn, thread int)) {
+	if r.threads == 1 || len(conns) < 2 {
+		for _, rc := range conns {
+			fn(rc, 0)
+		}
+		return
+	}
+
+	chunk := (len(conns) + r.threads - 1) / r.threads
+	var wg sync.WaitGroup
+	for t := 1; t*chunk < len(conns); t++ {
+		part := conns[t*chunk : min((t+1)*chunk, len(conns))]
+		wg.Add(1)
+		go func(t int) {
+			defer wg.Done()
+			for _, rc := range part {
+				fn(rc, t)
+			}
+		}(t)
+	}
+	for _, rc := range conns[:min(chunk, len(conns))] {
+		fn(rc, 0)
+	}
+	wg.Wait()
+}
+
+// readAndParse reads what is available and parses every complete request.
+func (rc *reactorConn) readAndParse(thread int) {
+	if rc.closing || rc.closed {
+		return
+	}
+
+	buf := rc.r.scratch[thread]
+	n, err := syscall.Read(rc.fd, buf)
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+		return
+	}
+	if err != nil || n == 0 {
+		rc.eof = true
+		return
+	}
+
+	data := buf[:n]
+	if len(rc.in) > 0 {
+		rc.in = append(rc.in, data...)
+		data = rc.in
+	}
+	if len(data) < rc.need {
+		if len(rc.in) == 0 {
+			rc.in = append(rc.in, data...)
+		}
+		return
+	}
+
+	end, malformed := 0, false
+	rc.need = 0
+	for end < len(data) {
+		l, need := resp.FrameLen(data[end:], rc.limits)
+		if l == 0 {
+			rc.need = need
+			break
+		}
+		if l < 0 {
+			end, malformed = len(data), true
+			break
+		}
+		end += l
+	}
+
+	rc.frame.Reset(data[:end])
+	rc.client.Reader.Reset(&rc.frame)
+	for rc.frame.Len() > 0 || rc.client.Reader.Buffered() > 0 {
+		v, err := rc.client.Reader.ReadCommand()
+		if err != nil {
+			// Complete frames only hit EOF after trailing empty lines.
+			if malformed || !errors.Is(err, io.EOF) {
+				rc.readErr = err
+			}
+			break
+		}
+		rc.cmds = append(rc.cmds, v)
+	}
+	if malformed && rc.readErr == nil {
+		rc.readErr = &resp.ProtocolError{Msg: "invalid request"}
+	}
+
+	// Keep the incomplete tail, copied out of the shared scratch buffer. If
+	// it is all of rc.in already, leave it in place.
+	if end > 0 || len(rc.in) == 0 {
+		rc.in = append(rc.in[:0], data[end:]...)
+	}
+}
+
+// execute runs the commands parsed for rc on the loop goroutine.
+func (r *reactor) execute(rc *reactorConn) {
+	c := rc.client
+	for _, v := range rc.cmds {
+		if reply := commands.HandleCommand(c, v, r.state); reply != nil {
+			_ = c.Write(reply)
+		}
+		if c.CloseAfterReply {
+			// Whatever was pipelined after QUIT is dropped.
+			rc.readErr, rc.closing = nil, true
+			break
+		}
+	}
+	clear(rc.cmds)
+	rc.cmds = rc.cmds[:0]
+
+	if rc.readErr != nil {
+		slog.Debug("Error deserializing request", "error", rc.readErr)
+		_ = c.Write(requestErrorReply(rc.readErr))
+		rc.readErr = nil
+		rc.closing = true
+	}
+	if rc.eof {
+		rc.closing = true
+	}
+	_ = c.Flush()
+	if rc.closing {
+		r.markDirty(rc)
+	}
+}
+
+// Write buffers encoded replies for the loop to send. It is only called on
+// the loop goroutine, from the client's RespWriter.
+func (rc *reactorConn) Write(p []byte) (int, error) {
+	if rc.closed {
+		return len(p), nil
+	}
+	rc.out = append(rc.out, p...)
+	rc.r.markDirty(rc)
+	return len(p), nil
+}
+
+func (r *reactor) markDirty(rc *reactorConn) {
+	if !rc.queued && !rc.closed {
+		rc.queued = true
+		r.dirty = append(r.dirty, rc)
+	}
+}
+
+// flush writes as much pending output as the socket accepts and arms
+// EPOLLOUT if some is left.
+func (rc *reactorConn) flush() {
+	if rc.closed {
+		return
+	}
+
+	written := 0
+	for written < len(rc.out) {
+		n, err := syscall.Write(rc.fd, rc.out[written:])
+		if errors.Is(err, syscall.EAGAIN) {
+			break
+		}
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			rc.failed = true
+			return
+		}
+		written += n
+	}
+
+	switch {
+	case written == len(rc.out) && cap(rc.out) > reactorKeepOut:
+		rc.out = nil
+	default:
+		rc.out = append(rc.out[:0], rc.out[written:]...)
+	}
+
+	if want := len(rc.out) > 0; want != rc.writing {
+		events := uint32(syscall.EPOLLIN)
+		if want {
+			events |= syscall.EPOLLOUT
+		}
+		ev := syscall.EpollEvent{Events: events, Fd: int32(rc.fd)}
+		if err := syscall.EpollCtl(rc.r.epfd, syscall.EPOLL_CTL_MOD, rc.fd, &ev); err != nil {
+			rc.failed = true
+			return
+		}
+		rc.writing = want
+	}
+}
+
+func (r *reactor) close(rc *reactorConn) {
+	if rc.closed {
+		return
+	}
+	rc.closed = true
+
+	_ = syscall.EpollCtl(r.epfd, syscall.EPOLL_CTL_DEL, rc.fd, nil)
+	delete(r.conns, rc.fd)
+	releaseClient(rc.client, r.state)
+	rc.conn.Close() //nolint:errcheck // nothing left to report to
+}
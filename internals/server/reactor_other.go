@@ -0,0 +1,14 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+
+	"github.com/shivakuppa/Go_Redis/internals/db"
+)
+
+func (s *Server) serveReactor(state *db.AppState, listeners ...net.Listener) error {
+	return errors.New("io-model reactor is only supported on Linux")
+}
@@ -1,62 +1,303 @@
 package server
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/shivakuppa/Go_Redis/config"
 	"github.com/shivakuppa/Go_Redis/internals/client"
 	"github.com/shivakuppa/Go_Redis/internals/db"
 )
 
-const defaultListenAddr = ":6379"
-
+// Server listens on the addresses in the configuration: the TCP port and
+// the TLS port on every bind address, and the unix socket.
 type Server struct {
-	ListenAddr string
-	Listener   net.Listener
+	Listeners    []net.Listener // plaintext TCP, one per bind address
+	TLSListeners []net.Listener
+	UnixListener net.Listener
+}
+
+func NewServer() *Server {
+	return &Server{}
 }
 
-func NewServer(listenAddr string) *Server {
-	if len(listenAddr) == 0 {
-		listenAddr = defaultListenAddr
+func (s *Server) Start(state *db.AppState) error {
+	conf := state.Config
+	if conf.Port == 0 && conf.UnixSocket == "" && conf.TLSPort == 0 {
+		return errors.New("no TCP port, TLS port or unix socket to listen on")
 	}
+	defer s.closeListeners() //nolint:errcheck // closed already unless failing
 
-	return &Server{
-		ListenAddr: listenAddr,
+	if conf.Port != 0 {
+		listeners, err := listenTCP(conf.Bind, conf.Port)
+		if err != nil {
+			return err
+		}
+		s.Listeners = listeners
+	}
+
+	if conf.UnixSocket != "" {
+		if err := s.listenUnix(state); err != nil {
+			return err
+		}
+	}
+
+	if conf.TLSPort != 0 {
+		if err := s.listenTLS(state); err != nil {
+			return err
+		}
+	}
+
+	// Clients may connect while the dataset loads; they get -LOADING until
+	// loadData is done.
+	state.Loading.Start("", 0)
+	acceptErr := make(chan error, 1)
+	go func() {
+		acceptErr <- s.serve(state)
+	}()
+
+	loaded := make(chan error, 1)
+	go func() {
+		loaded <- loadData(state)
+	}()
+
+	for {
+		select {
+		case err := <-loaded:
+			if err != nil {
+				slog.Error("Cannot load data from disk", "error", err)
+				return err
+			}
+		case err := <-acceptErr:
+			return err
+		case req := <-state.Shutdown.Requests():
+			if err := s.shutdown(state, req.Opts); err != nil {
+				req.Result <- err
+				continue
+			}
+			// The requester must hear back before its connection is
+			// closed: in reactor mode it holds up the event loop.
+			req.Result <- nil
+			return <-acceptErr
+		}
 	}
 }
 
-func (s *Server) Start(state *db.AppState) error {
-	listener, err := net.Listen("tcp", s.ListenAddr)
+// shutdown waits for the running commands, makes the dataset durable and
+// stops accepting connections. Once the accept loop sees the listener
+// closed, it closes every client connection and returns. If the dataset
+// cannot be saved, the server keeps running and an error is returned.
+func (s *Server) shutdown(state *db.AppState, opts db.ShutdownOptions) error {
+	state.Shutdown.Freeze()
+	if err := db.PrepareShutdown(state, opts); err != nil {
+		state.Shutdown.Thaw()
+		return err
+	}
+	state.Shutdown.Close()
+
+	return s.closeListeners()
+}
+
+// listenTCP listens on port on every bind address. Addresses with a "-"
+// prefix are skipped if they are unavailable; any other failure closes the
+// listeners opened so far.
+func listenTCP(bind []string, port int) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, b := range bind {
+		network, addr, optional := bindAddr(b, port)
+		l, err := net.Listen(network, addr)
+		if err != nil {
+			if optional {
+				slog.Warn("Skipping unavailable bind address", "addr", addr, "error", err)
+				continue
+			}
+			slog.Error("Cannot listen on port", "addr", addr, "error", err)
+			for _, l := range listeners {
+				l.Close() //nolint:errcheck // already failing
+			}
+			return nil, err
+		}
+		slog.Info("goredis server running", "listenAddr", l.Addr().String())
+		listeners = append(listeners, l)
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("failed listening on port %d: no bind address is available", port)
+	}
+	return listeners, nil
+}
+
+// bindAddr returns what to pass to net.Listen for the bind address b.
+// "*" and "::*" stand for every IPv4 and every IPv6 address. IP addresses
+// are bound in their own family only, so that "*" and "::*" can coexist.
+func bindAddr(b string, port int) (network, addr string, optional bool) {
+	host, optional := strings.CutPrefix(b, "-")
+	network = "tcp"
+	switch ip := net.ParseIP(host); {
+	case host == "*":
+		network, host = "tcp4", "0.0.0.0"
+	case host == "::*":
+		network, host = "tcp6", "::"
+	case ip == nil:
+	case ip.To4() != nil:
+		network = "tcp4"
+	default:
+		network = "tcp6"
+	}
+	return network, net.JoinHostPort(host, strconv.Itoa(port)), optional
+}
+
+// listenTLS loads the certificates and opens the TLS listeners.
+func (s *Server) listenTLS(state *db.AppState) error {
+	if err := state.TLS.Load(state.Config); err != nil {
+		slog.Error("Failed to configure TLS", "error", err)
+		return err
+	}
+
+	listeners, err := listenTCP(state.Config.Bind, state.Config.TLSPort)
+	if err != nil {
+		return err
+	}
+	for _, l := range listeners {
+		s.TLSListeners = append(s.TLSListeners, tls.NewListener(l, state.TLS.ServerConfig()))
+	}
+	return nil
+}
+
+// listenUnix opens the unix socket, replacing a stale socket file left by
+// a previous run, and applies unixsocketperm.
+func (s *Server) listenUnix(state *db.AppState) error {
+	path := state.Config.UnixSocket
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path) //nolint:errcheck // Listen reports the failure
+	}
+
+	listener, err := net.Listen("unix", path)
 	if err != nil {
-		slog.Error("Cannot listen on port", "addr", s.ListenAddr, "error", err)
+		slog.Error("Cannot listen on unix socket", "path", path, "error", err)
 		return err
 	}
+	s.UnixListener = listener
+
+	if perm := state.Config.UnixSocketPerm; perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			slog.Error("Cannot set unix socket permissions", "path", path, "error", err)
+			return err
+		}
+	}
+	slog.Info("goredis accepting connections on unix socket", "path", path)
+	return nil
+}
+
+// serve accepts connections on every listener until they are closed.
+// In reactor mode one event loop serves both TCP and unix socket clients.
+// TLS connections are always served by goroutines, as the reactor reads the
+// sockets directly; their commands then run alongside the loop, and reach
+// the loop's clients only through Push and Close.
+func (s *Server) serve(state *db.AppState) error {
+	plain := slices.Clone(s.Listeners)
+	if s.UnixListener != nil {
+		plain = append(plain, s.UnixListener)
+	}
+
+	errs := make(chan error, len(plain)+len(s.TLSListeners))
+	loops := 0
+	run := func(fn func() error) {
+		loops++
+		go func() { errs <- fn() }()
+	}
+	if len(plain) > 0 && state.Config.IOModel == config.ReactorIO {
+		run(func() error { return s.serveReactor(state, plain...) })
+	} else {
+		for _, l := range plain {
+			run(func() error { return s.acceptLoop(l, state) })
+		}
+	}
+	for _, l := range s.TLSListeners {
+		run(func() error { return s.acceptLoop(l, state) })
+	}
+
+	var err error
+	for range loops {
+		if e := <-errs; e != nil && err == nil {
+			// Stop the other loops too.
+			err = e
+			s.closeListeners() //nolint:errcheck // already failing
+		}
+	}
+	return err
+}
 
-	slog.Info("goredis server running", "listenAddr", s.ListenAddr)
-	defer listener.Close()
-	s.Listener = listener
+func (s *Server) closeListeners() error {
+	var errs []error
+	listeners := slices.Concat(s.Listeners, s.TLSListeners, []net.Listener{s.UnixListener})
+	for _, l := range listeners {
+		if l != nil {
+			if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	return s.acceptLoop(state)
+// acceptBackoff returns how long to wait after the n-th consecutive failed
+// Accept, e.g. while out of file descriptors.
+func acceptBackoff(n int) time.Duration {
+	return min(5*time.Millisecond<<min(n, 8), time.Second)
 }
 
-func (s *Server) acceptLoop(state *db.AppState) error {
+// acceptLoop serves each connection from l on its own goroutine. Once l is
+// closed it closes those connections and waits for them to finish.
+func (s *Server) acceptLoop(l net.Listener, state *db.AppState) error {
 	var wg sync.WaitGroup
 	defer wg.Wait() // ✅ wait for all connections only when shutting down
 
+	var mu sync.Mutex
+	live := map[*client.Client]struct{}{}
+
+	failures := 0
 	for {
-		conn, err := s.Listener.Accept()
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			mu.Lock()
+			for c := range live {
+				c.Conn.Close() //nolint:errcheck // shutting down
+			}
+			mu.Unlock()
+			return nil
+		}
 		if err != nil {
 			slog.Error("Error during accepting", "error", err)
+			time.Sleep(acceptBackoff(failures))
+			failures++
 			continue
 		}
+		failures = 0
 
 		c := client.NewClient(conn)
+		state.Clients.Add(c)
+		state.Stats.AddConnection()
+		mu.Lock()
+		live[c] = struct{}{}
+		mu.Unlock()
 
 		wg.Add(1)
 		go func(c *client.Client, appstate *db.AppState) {
 			defer wg.Done()
 			s.handleConnection(c, appstate)
+
+			mu.Lock()
+			delete(live, c)
+			mu.Unlock()
 		}(c, state)
 	}
 }
@@ -0,0 +1,113 @@
+// Package splitargs tokenizes a line into arguments with the quoting rules
+// shared by inline requests and redis.conf.
+package splitargs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrUnbalancedQuotes reports a quoted argument that is not closed, or whose
+// closing quote is not followed by whitespace.
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// Split splits a line into arguments like Redis' sdssplitargs: arguments
+// are separated by whitespace, "double quotes" support \n \r \t \b \a \\ \"
+// and \xHH escapes, and 'single quotes' only support \'. A closing quote must
+// be followed by whitespace or the end of the line.
+func Split(line string) ([]string, error) {
+	args := []string{}
+	i := 0
+
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var sb strings.Builder
+		switch line[i] {
+		case '"':
+			i++
+			for {
+				if i >= len(line) {
+					return nil, ErrUnbalancedQuotes
+				}
+				c := line[i]
+				if c == '"' {
+					i++
+					break
+				}
+				if c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]) {
+					b, _ := strconv.ParseUint(line[i+2:i+4], 16, 8)
+					sb.WriteByte(byte(b))
+					i += 4
+					continue
+				}
+				if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						c = '\n'
+					case 'r':
+						c = '\r'
+					case 't':
+						c = '\t'
+					case 'b':
+						c = '\b'
+					case 'a':
+						c = '\a'
+					default:
+						c = line[i]
+					}
+				}
+				sb.WriteByte(c)
+				i++
+			}
+			if i < len(line) && !isSpace(line[i]) {
+				return nil, ErrUnbalancedQuotes
+			}
+
+		case '\'':
+			i++
+			for {
+				if i >= len(line) {
+					return nil, ErrUnbalancedQuotes
+				}
+				c := line[i]
+				if c == '\'' {
+					i++
+					break
+				}
+				if c == '\\' && i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					c = '\''
+				}
+				sb.WriteByte(c)
+				i++
+			}
+			if i < len(line) && !isSpace(line[i]) {
+				return nil, ErrUnbalancedQuotes
+			}
+
+		default:
+			for i < len(line) && !isSpace(line[i]) {
+				sb.WriteByte(line[i])
+				i++
+			}
+		}
+
+		args = append(args, sb.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
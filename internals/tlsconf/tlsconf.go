@@ -0,0 +1,148 @@
+// Package tlsconf builds the server's TLS configuration from config.Config
+// and lets it be replaced while the server runs.
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/shivakuppa/Go_Redis/config"
+)
+
+// protocolVersions maps the tls-protocols names to TLS versions.
+var protocolVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": tls.VersionTLS13,
+}
+
+// Context holds the TLS configuration new connections are served with.
+// Connections that completed their handshake keep the configuration they
+// started with when it is reloaded.
+type Context struct {
+	current atomic.Pointer[tls.Config]
+}
+
+func New() *Context {
+	return &Context{}
+}
+
+// Load builds a configuration from conf and makes it current. On error the
+// current configuration is kept.
+func (c *Context) Load(conf *config.Config) error {
+	tc, err := Build(conf)
+	if err != nil {
+		return err
+	}
+	c.current.Store(tc)
+	return nil
+}
+
+// Loaded reports whether a configuration has been loaded.
+func (c *Context) Loaded() bool {
+	return c.current.Load() != nil
+}
+
+// ServerConfig returns the configuration to wrap a listener with. Each
+// handshake uses whatever configuration is current at the time.
+func (c *Context) ServerConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			tc := c.current.Load()
+			if tc == nil {
+				return nil, errors.New("TLS is not configured")
+			}
+			return tc, nil
+		},
+	}
+}
+
+// Build reads the certificate files named in conf and returns the
+// configuration they describe.
+func Build(conf *config.Config) (*tls.Config, error) {
+	if conf.TLSCertFile == "" || conf.TLSKeyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file must be specified")
+	}
+	cert, err := tls.LoadX509KeyPair(conf.TLSCertFile, conf.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", conf.TLSCertFile, err)
+	}
+
+	tc := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	switch conf.TLSAuthClients {
+	case config.TLSAuthNo:
+		tc.ClientAuth = tls.NoClientCert
+	case config.TLSAuthOptional:
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if conf.TLSCACertFile != "" {
+		pem, err := os.ReadFile(conf.TLSCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA certificate(s) file %s: %w", conf.TLSCACertFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no CA certificates found in %s", conf.TLSCACertFile)
+		}
+		tc.ClientCAs = pool
+	} else if tc.ClientAuth != tls.NoClientCert {
+		return nil, errors.New("tls-ca-cert-file must be specified when tls-auth-clients is enabled")
+	}
+
+	if tc.MinVersion, tc.MaxVersion, err = ParseProtocols(conf.TLSProtocols); err != nil {
+		return nil, err
+	}
+	if tc.CipherSuites, err = ParseCiphers(conf.TLSCiphers); err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+// ParseProtocols returns the lowest and highest of the space separated
+// protocol versions in s, e.g. "TLSv1.2 TLSv1.3". An empty s leaves both to
+// the Go defaults.
+func ParseProtocols(s string) (minVersion, maxVersion uint16, err error) {
+	for _, name := range strings.Fields(s) {
+		v, ok := protocolVersions[strings.ToLower(name)]
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid tls-protocols %q", name)
+		}
+		if minVersion == 0 || v < minVersion {
+			minVersion = v
+		}
+		maxVersion = max(maxVersion, v)
+	}
+	return minVersion, maxVersion, nil
+}
+
+// ParseCiphers returns the IDs of the colon separated TLS 1.2 cipher suites
+// in s, by their IANA names such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// TLS 1.3 suites are not configurable. An empty s selects the Go defaults.
+func ParseCiphers(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	known := map[string]uint16{}
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	var ids []uint16
+	for _, name := range strings.Split(s, ":") {
+		id, ok := known[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls-ciphers %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,352 @@
+// Package tracking implements server-assisted client-side caching. Clients
+// that enable CLIENT TRACKING are told, with an "invalidate" message, when a
+// key they may have cached is modified or expires.
+package tracking
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shivakuppa/Go_Redis/internals/client"
+	"github.com/shivakuppa/Go_Redis/internals/pubsub"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// Channel is where RESP2 clients receive invalidations redirected to them.
+const Channel = "__redis__:invalidate"
+
+var (
+	ErrRedirectMissing = errors.New("ERR The client ID you want redirect to does not exist")
+	ErrPrefixNoBCast   = errors.New("ERR PREFIX option requires BCAST mode to be enabled")
+	ErrOptInOptOut     = errors.New("ERR You can't use both OPTIN and OPTOUT")
+	ErrOptBCast        = errors.New("ERR OPTIN and OPTOUT are not compatible with BCAST")
+	ErrSwitchBCast     = errors.New("ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrSwitchOpt       = errors.New("ERR You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode.")
+	ErrCachingMode     = errors.New("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	ErrCachingYes      = errors.New("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+	ErrCachingNo       = errors.New("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+)
+
+// Options are the CLIENT TRACKING ON arguments.
+type Options struct {
+	BCast    bool
+	OptIn    bool
+	OptOut   bool
+	NoLoop   bool
+	Redirect int64 // client ID, 0 for none
+	Prefixes []string
+}
+
+type tracked struct {
+	c    *client.Client
+	opts Options
+
+	// caching is the CLIENT CACHING yes/no given for the next command.
+	caching    bool
+	cachingSet bool
+}
+
+// Table records which clients may have cached which keys. In the default
+// mode the keys a client reads are remembered and each is invalidated once,
+// the first time it changes; in BCAST mode clients get every change to keys
+// matching their prefixes instead.
+type Table struct {
+	mu       sync.Mutex
+	clients  map[int64]*tracked
+	keys     map[string]map[int64]struct{}
+	prefixes map[string]map[int64]struct{}
+
+	registry *client.Registry
+	hub      *pubsub.Hub
+}
+
+func NewTable(registry *client.Registry, hub *pubsub.Hub) *Table {
+	return &Table{
+		clients:  map[int64]*tracked{},
+		keys:     map[string]map[int64]struct{}{},
+		prefixes: map[string]map[int64]struct{}{},
+		registry: registry,
+		hub:      hub,
+	}
+}
+
+// Enable turns tracking on for c, or updates the redirect and prefixes if it
+// is already on.
+func (t *Table) Enable(c *client.Client, opts Options) error {
+	if len(opts.Prefixes) > 0 && !opts.BCast {
+		return ErrPrefixNoBCast
+	}
+	if opts.OptIn && opts.OptOut {
+		return ErrOptInOptOut
+	}
+	if opts.BCast && (opts.OptIn || opts.OptOut) {
+		return ErrOptBCast
+	}
+	if opts.Redirect != 0 && t.registry.Get(opts.Redirect) == nil {
+		return ErrRedirectMissing
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cur, ok := t.clients[c.ID]; ok {
+		if cur.opts.BCast != opts.BCast {
+			return ErrSwitchBCast
+		}
+		if cur.opts.OptIn != opts.OptIn || cur.opts.OptOut != opts.OptOut {
+			return ErrSwitchOpt
+		}
+		for _, p := range cur.opts.Prefixes {
+			if !slices.Contains(opts.Prefixes, p) {
+				opts.Prefixes = append(opts.Prefixes, p)
+			}
+		}
+	}
+	if opts.BCast && len(opts.Prefixes) == 0 {
+		opts.Prefixes = []string{""}
+	}
+
+	t.clients[c.ID] = &tracked{c: c, opts: opts}
+	for _, p := range opts.Prefixes {
+		subs, ok := t.prefixes[p]
+		if !ok {
+			subs = map[int64]struct{}{}
+			t.prefixes[p] = subs
+		}
+		subs[c.ID] = struct{}{}
+	}
+	return nil
+}
+
+// Disable turns tracking off for c. Keys it read stay in the table until
+// they are next invalidated, at which point c is skipped.
+func (t *Table) Disable(c *client.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[c.ID]
+	if !ok {
+		return
+	}
+	for _, p := range tc.opts.Prefixes {
+		delete(t.prefixes[p], c.ID)
+		if len(t.prefixes[p]) == 0 {
+			delete(t.prefixes, p)
+		}
+	}
+	delete(t.clients, c.ID)
+}
+
+// Options returns c's tracking options and whether tracking is on.
+func (t *Table) Options(c *client.Client) (Options, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[c.ID]
+	if !ok {
+		return Options{}, false
+	}
+	return tc.opts, true
+}
+
+// SetCaching implements CLIENT CACHING: it decides whether the keys read by
+// c's next command are tracked.
+func (t *Table) SetCaching(c *client.Client, yes bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[c.ID]
+	if !ok || !(tc.opts.OptIn || tc.opts.OptOut) {
+		return ErrCachingMode
+	}
+	if yes && !tc.opts.OptIn {
+		return ErrCachingYes
+	}
+	if !yes && !tc.opts.OptOut {
+		return ErrCachingNo
+	}
+	tc.caching, tc.cachingSet = yes, true
+	return nil
+}
+
+// AfterCommand clears the CLIENT CACHING answer once the command it applied
+// to has run.
+func (t *Table) AfterCommand(c *client.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if tc, ok := t.clients[c.ID]; ok {
+		tc.caching, tc.cachingSet = false, false
+	}
+}
+
+// TrackRead remembers that c read keys, so that it is told when they change.
+func (t *Table) TrackRead(c *client.Client, keys ...string) {
+	if c == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[c.ID]
+	if !ok || tc.opts.BCast {
+		return
+	}
+	if tc.opts.OptIn && !(tc.cachingSet && tc.caching) {
+		return
+	}
+	if tc.opts.OptOut && tc.cachingSet && !tc.caching {
+		return
+	}
+
+	for _, key := range keys {
+		subs, ok := t.keys[key]
+		if !ok {
+			subs = map[int64]struct{}{}
+			t.keys[key] = subs
+		}
+		subs[c.ID] = struct{}{}
+	}
+}
+
+// Invalidate reports that key was modified by c, or by the server itself
+// (e.g. on expiry) when c is nil.
+func (t *Table) Invalidate(c *client.Client, key string) {
+	t.mu.Lock()
+	var targets []*tracked
+	seen := map[int64]bool{}
+	add := func(id int64) {
+		tc, ok := t.clients[id]
+		if !ok || seen[id] {
+			return
+		}
+		seen[id] = true
+		if tc.opts.NoLoop && c != nil && c.ID == id {
+			return
+		}
+		targets = append(targets, tc)
+	}
+
+	for id := range t.keys[key] {
+		add(id)
+	}
+	delete(t.keys, key)
+
+	for p, subs := range t.prefixes {
+		if strings.HasPrefix(key, p) {
+			for id := range subs {
+				add(id)
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	keys := &resp.Value{Type: resp.Array, Array: []*resp.Value{{Type: resp.BulkString, String: key}}}
+	for _, tc := range targets {
+		t.send(tc, keys)
+	}
+}
+
+// InvalidateAll tells every tracking client to drop its whole cache, as
+// after FLUSHDB.
+func (t *Table) InvalidateAll() {
+	t.mu.Lock()
+	targets := make([]*tracked, 0, len(t.clients))
+	for _, tc := range t.clients {
+		targets = append(targets, tc)
+	}
+	t.keys = map[string]map[int64]struct{}{}
+	t.mu.Unlock()
+
+	for _, tc := range targets {
+		t.send(tc, &resp.Value{Type: resp.Null, IsNull: true})
+	}
+}
+
+// send delivers an invalidation to tc, or to the client it redirects to.
+// RESP2 connections can only receive invalidations through a redirect to a
+// connection subscribed to Channel.
+func (t *Table) send(tc *tracked, keys *resp.Value) {
+	target := tc.c
+	if tc.opts.Redirect != 0 {
+		target = t.registry.Get(tc.opts.Redirect)
+		if target == nil {
+			if tc.c.RESP3() {
+				_ = tc.c.Push(&resp.Value{Type: resp.Push, Array: []*resp.Value{
+					{Type: resp.BulkString, String: "tracking-redir-broken"},
+					{Type: resp.Integer, Integer: tc.opts.Redirect},
+				}})
+			}
+			return
+		}
+	}
+
+	switch {
+	case target.RESP3():
+		_ = target.Push(&resp.Value{Type: resp.Push, Array: []*resp.Value{
+			{Type: resp.BulkString, String: "invalidate"},
+			keys,
+		}})
+	case tc.opts.Redirect != 0 && t.hub.IsSubscribed(target, Channel):
+		if keys.Type == resp.Null {
+			keys = &resp.Value{Type: resp.Array, IsNull: true}
+		}
+		_ = target.Push(pubsub.Message(target, "message", Channel, keys))
+	}
+}
+
+// Len returns how many clients have tracking on.
+func (t *Table) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.clients)
+}
+
+// Info describes c's tracking state for CLIENT TRACKINGINFO.
+func (t *Table) Info(c *client.Client) (flags []string, redirect int64, prefixes []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[c.ID]
+	if !ok {
+		return []string{"off"}, -1, []string{}
+	}
+
+	flags = []string{"on"}
+	if tc.opts.BCast {
+		flags = append(flags, "bcast")
+	}
+	if tc.opts.OptIn {
+		flags = append(flags, "optin")
+		if tc.cachingSet && tc.caching {
+			flags = append(flags, "caching-yes")
+		}
+	}
+	if tc.opts.OptOut {
+		flags = append(flags, "optout")
+		if tc.cachingSet && !tc.caching {
+			flags = append(flags, "caching-no")
+		}
+	}
+	if tc.opts.NoLoop {
+		flags = append(flags, "noloop")
+	}
+	if tc.opts.Redirect != 0 && t.registry.Get(tc.opts.Redirect) == nil {
+		flags = append(flags, "broken_redirect")
+	}
+
+	prefixes = []string{}
+	if tc.opts.BCast {
+		for _, p := range tc.opts.Prefixes {
+			if p != "" {
+				prefixes = append(prefixes, p)
+			}
+		}
+		sort.Strings(prefixes)
+	}
+	return flags, tc.opts.Redirect, prefixes
+}
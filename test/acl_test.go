@@ -0,0 +1,140 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	_ "github.com/shivakuppa/Go_Redis/internals/commands" // validates command names in rules
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []string
+		cmd   string
+		sub   string
+		cats  []string
+		want  bool
+	}{
+		{"new users may do nothing", nil, "get", "", []string{"read"}, false},
+		{"category", []string{"+@read"}, "get", "", []string{"read"}, true},
+		{"command removed from category", []string{"+@read", "-get"}, "get", "", []string{"read"}, false},
+		{"later rules win", []string{"-get", "+@read"}, "get", "", []string{"read"}, true},
+		{"all resets", []string{"+get", "-@all"}, "get", "", []string{"read"}, false},
+		{"subcommand", []string{"+config|get"}, "config", "get", []string{"admin"}, true},
+		{"other subcommand", []string{"+config|get"}, "config", "set", []string{"admin"}, false},
+		{"subcommand removed", []string{"+config", "-config|set"}, "config", "set", []string{"admin"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := acl.NewUser("u")
+			require.NoError(t, u.Apply(tt.rules...))
+			assert.Equal(t, tt.want, u.CanRun(tt.cmd, tt.sub, tt.cats))
+		})
+	}
+}
+
+func TestUserKeysAndChannels(t *testing.T) {
+	u := acl.NewUser("u")
+	require.NoError(t, u.Apply("~app:*", "%R~config:[ab]?", "%W~log:*", "&news.*"))
+
+	assert.True(t, u.CanAccessKey("app:users/1", true, true), "* matches /")
+	assert.True(t, u.CanAccessKey("config:a1", true, false))
+	assert.False(t, u.CanAccessKey("config:a1", false, true))
+	assert.False(t, u.CanAccessKey("config:c1", true, false))
+	assert.True(t, u.CanAccessKey("log:1", false, true))
+	assert.False(t, u.CanAccessKey("log:1", true, true))
+	assert.True(t, u.CanAccessChannel("news.sport"))
+	assert.False(t, u.CanAccessChannel("chat"))
+
+	assert.Equal(t, "~app:* %R~config:[ab]? %W~log:*", u.KeyRules())
+}
+
+func TestUserRuleErrors(t *testing.T) {
+	for _, rule := range []string{"+nosuchcommand", "+@nosuchcategory", "%X~k", "<notapassword", "#abc", "bogus"} {
+		err := acl.NewUser("u").Apply(rule)
+		assert.Error(t, err, rule)
+	}
+
+	a := acl.New()
+	require.NoError(t, a.SetUser("alice", "on", "+get"))
+	assert.Error(t, a.SetUser("alice", "+set", "+nosuchcommand"))
+	assert.Equal(t, "-@all +get", a.User("alice").CommandRules(), "failed SETUSER changes nothing")
+
+	_, err := a.DelUser("default")
+	assert.ErrorIs(t, err, acl.ErrDefaultUser)
+}
+
+func TestACLFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+
+	a := acl.New()
+	require.NoError(t, a.SetUser("alice", "on", ">pw", "~cache:*", "%R~cfg:*", "&news", "+@read", "-keys"))
+	require.NoError(t, a.SetUser("default", "resetpass", ">admin"))
+	require.NoError(t, a.SaveFile(path))
+
+	b := acl.New()
+	require.NoError(t, b.LoadFile(path))
+	for _, name := range []string{"alice", "default"} {
+		assert.Equal(t, a.User(name).Rules(), b.User(name).Rules(), name)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("user bob on +nosuchcommand\n"), 0o644))
+	err := b.LoadFile(path)
+	assert.ErrorContains(t, err, "users.acl:1")
+	assert.NotNil(t, b.User("alice"), "a bad file changes nothing")
+}
+
+func TestACLCommands(t *testing.T) {
+	aclFile := filepath.Join(t.TempDir(), "users.acl")
+	require.NoError(t, os.WriteFile(aclFile, nil, 0o644))
+	addr := startServer(t, func(c *config.Config) { c.ACLFile = aclFile })
+	admin := dial(t, addr)
+	admin.send("HELLO 3\r\n")
+
+	reply := admin.send("ACL SETUSER alice on >pw ~cache:* %R~cfg:* &news.* +@read +@write +@pubsub -del +acl|whoami\r\n")
+	require.Equal(t, resp.OK, reply)
+	assert.Equal(t, resp.OK, admin.send("ACL DRYRUN alice GET cache:1\r\n"))
+	assert.Equal(t, "No permissions to access a key", admin.send("ACL DRYRUN alice SET cfg:1 v\r\n").String)
+
+	alice := dial(t, addr)
+	require.Equal(t, resp.OK, alice.send("AUTH alice pw\r\n"))
+	assert.Equal(t, "alice", alice.send("ACL WHOAMI\r\n").String)
+	assert.Equal(t, resp.OK, alice.send("SET cache:1 v\r\n"))
+	assert.Equal(t, "v", alice.send("GET cache:1\r\n").String)
+	assert.True(t, alice.send("GET cfg:1\r\n").IsNull)
+	assert.Equal(t, "NOPERM No permissions to access a key", alice.send("SET cfg:1 v\r\n").String)
+	assert.Equal(t, "NOPERM No permissions to access a key", alice.send("MSET cache:2 v other v\r\n").String)
+	assert.Equal(t, "NOPERM User alice has no permissions to run the 'del' command", alice.send("DEL cache:1\r\n").String)
+	assert.Equal(t, "NOPERM User alice has no permissions to run the 'acl|users' command", alice.send("ACL USERS\r\n").String)
+	assert.Equal(t, int64(0), alice.send("PUBLISH news.today hi\r\n").Integer)
+	assert.Equal(t, "NOPERM No permissions to access a channel", alice.send("PUBLISH chat hi\r\n").String)
+
+	log := admin.send("ACL LOG 1\r\n")
+	require.Len(t, log.Array, 1)
+	for field, want := range map[string]string{"reason": "channel", "object": "chat", "username": "alice"} {
+		got, ok := log.Array[0].Lookup(field)
+		require.True(t, ok, field)
+		assert.Equal(t, want, got.String, field)
+	}
+	assert.Equal(t, resp.OK, admin.send("ACL LOG RESET\r\n"))
+	assert.Empty(t, admin.send("ACL LOG\r\n").Array)
+
+	keys, _ := admin.send("ACL GETUSER alice\r\n").Lookup("keys")
+	assert.Equal(t, "~cache:* %R~cfg:*", keys.String)
+
+	// Users survive a SAVE and LOAD round trip, and so do their connections.
+	require.Equal(t, resp.OK, admin.send("ACL SAVE\r\n"))
+	require.Equal(t, resp.OK, admin.send("ACL LOAD\r\n"))
+	assert.Equal(t, "alice", alice.send("ACL WHOAMI\r\n").String)
+
+	assert.Equal(t, int64(1), admin.send("ACL DELUSER alice\r\n").Integer)
+	_, err := alice.reader.ReadValue()
+	assert.Error(t, err, "clients of deleted users are disconnected")
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/acl"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withPassword(pass string) func(*config.Config) {
+	return func(c *config.Config) {
+		c.Requirepass = true
+		c.Password = pass
+	}
+}
+
+func TestAuth(t *testing.T) {
+	addr := startServer(t, withPassword("s3cret"))
+
+	t.Run("commands need auth", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.Equal(t, "NOAUTH Authentication required.", c.send("PING\r\n").String)
+		assert.Equal(t, "NOAUTH Authentication required.", c.send("GET k\r\n").String)
+
+		reply := c.send("AUTH wrong\r\n")
+		assert.Equal(t, "WRONGPASS invalid username-password pair or user is disabled.", reply.String)
+		assert.Equal(t, "NOAUTH Authentication required.", c.send("PING\r\n").String)
+
+		assert.Equal(t, resp.OK, c.send("AUTH s3cret\r\n"))
+		assert.Equal(t, "PONG", c.send("PING\r\n").String)
+	})
+
+	t.Run("username", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.Equal(t, resp.SimpleError, c.send("AUTH alice s3cret\r\n").Type)
+		assert.Equal(t, resp.OK, c.send("AUTH default s3cret\r\n"))
+		assert.Equal(t, "ERR syntax error", c.send("AUTH a b c\r\n").String)
+	})
+
+	t.Run("hello auth", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.Equal(t, resp.SimpleError, c.send("HELLO 3 AUTH default nope\r\n").Type)
+		assert.Equal(t, resp.Map, c.send("HELLO 3 AUTH default s3cret\r\n").Type)
+		assert.Equal(t, "PONG", c.send("PING\r\n").String)
+	})
+
+	t.Run("hello needs auth", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.True(t, strings.HasPrefix(c.send("HELLO 3\r\n").String, "NOAUTH HELLO must be called"))
+
+		// A bad option is rejected before the credentials are used.
+		reply := c.send("HELLO 3 AUTH default s3cret SETNAME \"a b\"\r\n")
+		assert.Contains(t, reply.String, "Client names cannot contain spaces")
+		assert.Equal(t, "NOAUTH Authentication required.", c.send("PING\r\n").String)
+	})
+
+	t.Run("quit", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.Equal(t, resp.OK, c.send("QUIT\r\n"))
+		_, err := c.reader.ReadValue()
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestAuthWithoutPassword(t *testing.T) {
+	c := dial(t, startServer(t))
+	assert.Contains(t, c.send("AUTH pass\r\n").String, "without any password configured")
+	assert.Equal(t, resp.OK, c.send("AUTH default anything\r\n"))
+}
+
+func TestAuthLimiter(t *testing.T) {
+	l := acl.NewLimiter()
+	l.MaxFailures = 2
+	l.BaseDelay = 50 * time.Millisecond
+
+	l.Fail("10.0.0.1")
+	assert.True(t, l.Allow("10.0.0.1"))
+	l.Fail("10.0.0.1")
+	assert.False(t, l.Allow("10.0.0.1"))
+	assert.True(t, l.Allow("10.0.0.2"), "other hosts are not affected")
+
+	require.Eventually(t, func() bool { return l.Allow("10.0.0.1") }, time.Second, 5*time.Millisecond)
+
+	// Each further failure doubles the delay.
+	l.Fail("10.0.0.1")
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, l.Allow("10.0.0.1"))
+
+	l.Succeed("10.0.0.1")
+	assert.True(t, l.Allow("10.0.0.1"))
+}
+
+func TestPasswordsAreHashed(t *testing.T) {
+	a := acl.New()
+	require.NoError(t, a.SetUser("alice", "on", ">s3cret"))
+
+	u := a.User("alice")
+	assert.Equal(t, []string{acl.HashPassword("s3cret")}, u.Passwords)
+	assert.NotContains(t, u.Rules(), "s3cret")
+
+	_, err := a.Authenticate("alice", "s3cret")
+	assert.NoError(t, err)
+	_, err = a.Authenticate("alice", "s3cret ")
+	assert.ErrorIs(t, err, acl.ErrWrongPass)
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withBind(addrs ...string) func(*config.Config) {
+	return func(c *config.Config) {
+		c.Bind = addrs
+	}
+}
+
+// externalIP returns a non-loopback IPv4 address of this host, to connect
+// from as a remote client would.
+func externalIP(t *testing.T) string {
+	t.Helper()
+	addrs, err := net.InterfaceAddrs()
+	require.NoError(t, err)
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	t.Skip("no non-loopback IPv4 address")
+	return ""
+}
+
+func TestBind(t *testing.T) {
+	t.Run("optional addresses may be unavailable", func(t *testing.T) {
+		// 192.0.2.0/24 is reserved for documentation.
+		addr := startServer(t, withBind("127.0.0.1", "-192.0.2.123", "-::1"))
+		assert.Equal(t, "PONG", dial(t, addr).send("PING\r\n").String)
+
+		bind := dial(t, addr).send("CONFIG GET bind\r\n")
+		require.Len(t, bind.Array, 2)
+		assert.Equal(t, "127.0.0.1 -192.0.2.123 -::1", bind.Array[1].String)
+	})
+
+	t.Run("required addresses must be available", func(t *testing.T) {
+		conf := config.NewConfig()
+		conf.Dir = t.TempDir()
+		conf.Bind = []string{"127.0.0.1", "192.0.2.123"}
+		conf.Port = freePort(t)
+		assert.Error(t, server.NewServer().Start(db.NewAppState(conf)))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		l, err := net.Listen("tcp6", "[::1]:0")
+		if err != nil {
+			t.Skip("no IPv6 loopback")
+		}
+		require.NoError(t, l.Close())
+
+		addr := startServer(t, withBind("127.0.0.1", "::1"))
+		_, port, err := net.SplitHostPort(addr)
+		require.NoError(t, err)
+		conn := dial(t, net.JoinHostPort("::1", port))
+		assert.Equal(t, "PONG", conn.send("PING\r\n").String)
+	})
+}
+
+func TestProtectedMode(t *testing.T) {
+	ip := externalIP(t)
+	for _, model := range []config.IOModel{config.GoroutineIO, config.ReactorIO} {
+		t.Run(string(model), func(t *testing.T) {
+			addr := startServer(t, withBind("127.0.0.1", ip), withIOModel(model, 1))
+			_, port, err := net.SplitHostPort(addr)
+			require.NoError(t, err)
+			external := net.JoinHostPort(ip, port)
+			local := dial(t, addr)
+
+			conn := dial(t, external)
+			reply := conn.read()
+			assert.Equal(t, resp.SimpleError, reply.Type)
+			assert.Contains(t, reply.String, "DENIED Redis is running in protected mode")
+			_, err = conn.reader.ReadValue()
+			assert.Error(t, err, "the connection is closed")
+
+			assert.Equal(t, "PONG", local.send("PING\r\n").String, "loopback clients are served")
+
+			require.Equal(t, resp.OK, local.send("CONFIG SET protected-mode no\r\n"))
+			assert.Equal(t, "PONG", dial(t, external).send("PING\r\n").String)
+
+			// With a password, remote clients may connect and authenticate.
+			require.Equal(t, resp.OK, local.send("CONFIG SET protected-mode yes\r\n"))
+			require.Equal(t, resp.OK, local.send("ACL SETUSER default >secret\r\n"))
+			conn = dial(t, external)
+			assert.Equal(t, "NOAUTH Authentication required.", conn.send("PING\r\n").String)
+			assert.Equal(t, resp.OK, conn.send("AUTH secret\r\n"))
+		})
+	}
+}
+
+func TestPortZeroDisablesTCP(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Port = 0
+	assert.ErrorContains(t, server.NewServer().Start(db.NewAppState(conf)), "no TCP port")
+}
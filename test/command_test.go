@@ -0,0 +1,114 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkStrings(v *resp.Value) []string {
+	var ss []string
+	for _, e := range v.Array {
+		ss = append(ss, e.String)
+	}
+	return ss
+}
+
+func TestCommand(t *testing.T) {
+	conn := dial(t, startServer(t))
+	conn.send("HELLO 3\r\n")
+
+	all := conn.send("COMMAND\r\n")
+	require.Equal(t, resp.Array, all.Type)
+	assert.Equal(t, int64(len(all.Array)), conn.send("COMMAND COUNT\r\n").Integer)
+
+	info := conn.send("COMMAND INFO get nosuch config|get\r\n")
+	require.Len(t, info.Array, 3)
+	get := info.Array[0].Array
+	require.Len(t, get, 10)
+	assert.Equal(t, "get", get[0].String)
+	assert.Equal(t, int64(2), get[1].Integer)
+	assert.True(t, get[2].Contains(&resp.Value{Type: resp.SimpleString, String: "readonly"}))
+	assert.Equal(t, []int64{1, 1, 1}, []int64{get[3].Integer, get[4].Integer, get[5].Integer})
+	assert.True(t, get[6].Contains(&resp.Value{Type: resp.SimpleString, String: "@read"}))
+	require.Len(t, get[8].Array, 1, "one key spec")
+	assert.True(t, info.Array[1].IsNull, "unknown commands are null")
+	assert.Equal(t, "config|get", info.Array[2].Array[0].String)
+	assert.Equal(t, int64(-3), info.Array[2].Array[1].Integer)
+
+	mset := conn.send("COMMAND INFO mset\r\n").Array[0].Array
+	assert.Equal(t, []int64{1, -1, 2}, []int64{mset[3].Integer, mset[4].Integer, mset[5].Integer})
+
+	docs := conn.send("COMMAND DOCS config\r\n")
+	cfg, ok := docs.Lookup("config")
+	require.True(t, ok)
+	group, _ := cfg.Lookup("group")
+	assert.Equal(t, "server", group.String)
+	subs, ok := cfg.Lookup("subcommands")
+	require.True(t, ok)
+	_, ok = subs.Lookup("config|set")
+	assert.True(t, ok)
+
+	assert.Contains(t, bulkStrings(conn.send("COMMAND LIST\r\n")), "client|setname")
+	assert.Equal(t, []string{"config|get", "config|resetstat", "config|rewrite", "config|set"},
+		bulkStrings(conn.send("COMMAND LIST FILTERBY PATTERN config|*\r\n")))
+	assert.Contains(t, bulkStrings(conn.send("COMMAND LIST FILTERBY ACLCAT keyspace\r\n")), "del")
+	assert.Empty(t, conn.send("COMMAND LIST FILTERBY MODULE json\r\n").Array)
+
+	assert.Equal(t, []string{"a", "b"}, bulkStrings(conn.send("COMMAND GETKEYS MSET a 1 b 2\r\n")))
+	assert.Equal(t, []string{"src", "dst"}, bulkStrings(conn.send("COMMAND GETKEYS RENAME src dst\r\n")))
+	assert.Equal(t, "ERR The command has no key arguments", conn.send("COMMAND GETKEYS PING\r\n").String)
+	assert.Equal(t, "ERR Invalid command specified", conn.send("COMMAND GETKEYS NOSUCH a\r\n").String)
+	assert.Equal(t, "ERR Invalid number of arguments specified for command", conn.send("COMMAND GETKEYS GET\r\n").String)
+}
+
+func TestCommandTable(t *testing.T) {
+	conn := dial(t, startServer(t))
+
+	names := bulkStrings(conn.send("COMMAND LIST\r\n"))
+	require.NotEmpty(t, names)
+	for _, name := range names {
+		info := conn.send("COMMAND INFO " + name + "\r\n")
+		require.Len(t, info.Array, 1, name)
+		require.False(t, info.Array[0].IsNull, "%s has metadata", name)
+		assert.NotZero(t, info.Array[0].Array[1].Integer, "%s has an arity", name)
+	}
+
+	// Every command named by COMMAND is dispatched rather than unknown.
+	for _, cmd := range conn.send("COMMAND\r\n").Array {
+		name := cmd.Array[0].String
+		reply := conn.send("COMMAND GETKEYS " + name + "\r\n")
+		assert.NotEqual(t, "ERR Invalid command specified", reply.String, name)
+	}
+}
+
+func TestCommandValidation(t *testing.T) {
+	conn := dial(t, startServer(t))
+
+	assert.Equal(t, "PONG", conn.send("*0\r\nPING\r\n").String, "empty requests are ignored")
+	for cmd, want := range map[string]string{
+		"GET\r\n":             "ERR wrong number of arguments for 'get' command",
+		"SAVE now\r\n":        "ERR wrong number of arguments for 'save' command",
+		"CONFIG\r\n":          "ERR wrong number of arguments for 'config' command",
+		"CONFIG GET\r\n":      "ERR wrong number of arguments for 'config|get' command",
+		"CONFIG NOPE\r\n":     "ERR unknown subcommand 'NOPE'. Try CONFIG HELP.",
+		"FOO bar baz\r\n":     "ERR unknown command 'FOO', with args beginning with: 'bar' 'baz' ",
+		"FOO\r\n":             "ERR unknown command 'FOO', with args beginning with: ",
+		"SET k v EX 10\r\n":   "ERR syntax error",
+		"MSET a 1 b\r\n":      "ERR wrong number of arguments for 'mset' command",
+		"EXPIRE k 10 NX\r\n":  "ERR Unsupported option NX",
+		"CLIENT SETNAME\r\n":  "ERR wrong number of arguments for 'client|setname' command",
+		"ACL GETUSER a b\r\n": "ERR wrong number of arguments for 'acl|getuser' command",
+	} {
+		reply := conn.send(cmd)
+		assert.Equal(t, resp.SimpleError, reply.Type, cmd)
+		assert.Equal(t, want, reply.String, cmd)
+	}
+
+	reply := conn.send("*2\r\n$3\r\nGET\r\n:1\r\n")
+	assert.Equal(t, "ERR Protocol error: expected '$', got ':'", reply.String)
+	_, err := conn.reader.ReadValue()
+	assert.Error(t, err, "the connection is closed")
+}
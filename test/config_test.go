@@ -0,0 +1,234 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "tls.conf", "tls-port 6380\ntls-protocols \"TLSv1.2 TLSv1.3\"\n")
+	path := writeConfig(t, dir, "redis.conf", `
+# comments and blank lines are skipped
+dir `+filepath.Join(dir, "data")+`
+	port	7000
+port 7001
+save 900 1
+save 300 10
+requirepass "pa ss\"word\x21"
+proto-max-bulk-len 1mb
+appendfsync ALWAYS
+bind 127.0.0.1 -::1
+user alice on '>wonder land' ~cache:*
+include tls.conf
+`)
+
+	c, err := config.Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7001, c.Port, "the last of duplicate directives wins")
+	assert.Equal(t, []config.RDBSnapshot{{Secs: 900, KeysChanged: 1}, {Secs: 300, KeysChanged: 10}}, c.RDB, "save lines accumulate")
+	assert.Equal(t, `pa ss"word!`, c.Password)
+	assert.True(t, c.Requirepass)
+	assert.Equal(t, int64(1<<20), c.ProtoMaxBulkLen)
+	assert.Equal(t, config.Always, c.AOFfsync)
+	assert.Equal(t, []string{"127.0.0.1", "-::1"}, c.Bind)
+	assert.Equal(t, [][]string{{"alice", "on", ">wonder land", "~cache:*"}}, c.Users)
+	assert.Equal(t, 6380, c.TLSPort, "include is relative to the including file")
+	assert.Equal(t, "TLSv1.2 TLSv1.3", c.TLSProtocols)
+	assert.DirExists(t, c.Dir)
+
+	c, err = config.Load(path, [][]string{{"port", "7002"}, {"save", ""}, {"appendonly", "yes"}})
+	require.NoError(t, err)
+	assert.Equal(t, 7002, c.Port)
+	assert.Empty(t, c.RDB, `save "" clears the save points`)
+	assert.True(t, c.AOFenabled)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, content, err string
+	}{
+		{"blank save", "port 7000\nsave\n", "redis.conf:2: wrong number of arguments for 'save'"},
+		{"unknown directive", "maxclients 10\n", "redis.conf:1: unknown directive 'maxclients'"},
+		{"missing argument", "dir\n", "redis.conf:1: wrong number of arguments for 'dir'"},
+		{"extra argument", "port 1 2\n", "redis.conf:1: wrong number of arguments for 'port'"},
+		{"bad yes/no", "\n\nappendonly maybe\n", "redis.conf:3: invalid 'appendonly': argument must be 'yes' or 'no'"},
+		{"bad number", "port 70000\n", "redis.conf:1: invalid 'port'"},
+		{"bad unit", "proto-max-bulk-len 1xb\n", "redis.conf:1: invalid 'proto-max-bulk-len'"},
+		{"memory overflow", "proto-max-bulk-len 9000000000gb\n", "redis.conf:1: invalid 'proto-max-bulk-len'"},
+		{"path as filename", "dbfilename ../dump.rdb\n", "redis.conf:1: invalid 'dbfilename': dbfilename can't be a path, just a filename"},
+		{"unbalanced quotes", "requirepass \"secret\n", "redis.conf:1: "},
+		{"include loop", "include redis.conf\n", "includes nested more than"},
+		{"missing include", "include nope.conf\n", "redis.conf:1: open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, t.TempDir(), "redis.conf", tt.content)
+			_, err := config.Load(path, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := config.Load("", [][]string{{"io-threads", "0"}})
+	assert.ErrorContains(t, err, "command line: --io-threads: invalid 'io-threads'")
+}
+
+func TestShippedConfigLoads(t *testing.T) {
+	c, err := config.Load("../config/redis.conf", [][]string{{"dir", t.TempDir()}})
+	require.NoError(t, err)
+	assert.True(t, c.Requirepass)
+}
+
+func TestParseArgs(t *testing.T) {
+	path, overrides, err := config.ParseArgs([]string{"redis.conf", "--port", "7000", "--save", "", "--bind", "127.0.0.1", "::1"})
+	require.NoError(t, err)
+	assert.Equal(t, "redis.conf", path)
+	assert.Equal(t, [][]string{{"port", "7000"}, {"save", ""}, {"bind", "127.0.0.1", "::1"}}, overrides)
+
+	path, overrides, err = config.ParseArgs([]string{"--port", "7000"})
+	require.NoError(t, err)
+	assert.Empty(t, path)
+	assert.Equal(t, [][]string{{"port", "7000"}}, overrides)
+
+	_, _, err = config.ParseArgs([]string{"redis.conf", "7000"})
+	assert.Error(t, err)
+}
+
+func TestConfigGetSet(t *testing.T) {
+	c := config.NewConfig()
+	for _, name := range config.Params() {
+		val, ok := c.Get(name)
+		require.True(t, ok, name)
+		if err := c.Set(name, val); errors.Is(err, config.ErrImmutableParam) {
+			continue
+		} else {
+			require.NoError(t, err, name)
+		}
+		got, _ := c.Get(name)
+		assert.Equal(t, val, got, "%s round-trips", name)
+	}
+
+	require.NoError(t, c.Set("save", "60 100 10 1000"))
+	require.NoError(t, c.Set("SAVE", "30 5"))
+	assert.Equal(t, []config.RDBSnapshot{{Secs: 30, KeysChanged: 5}}, c.RDB, "save replaces the save points")
+
+	assert.ErrorIs(t, c.Set("no-such-param", "1"), config.ErrUnknownParam)
+	assert.ErrorIs(t, c.Set("port", "7000"), config.ErrImmutableParam)
+	assert.Error(t, c.Set("save", "60 100 oops"))
+	assert.Equal(t, []config.RDBSnapshot{{Secs: 30, KeysChanged: 5}}, c.RDB, "a failed set changes nothing")
+}
+
+func TestConfigRewrite(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "redis.conf", `# my settings
+dir `+dir+`
+save 900 1
+save 300 10
+
+# the port
+port 7000
+`)
+
+	c, err := config.Load(path, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Set("save", "60 5"))
+	require.NoError(t, c.Set("requirepass", `a "quoted" pass\word`))
+	require.NoError(t, c.Set("appendonly", "yes"))
+	c.Users = [][]string{{"alice", "on", ">wonder land", "~cache:*"}}
+	require.NoError(t, config.Rewrite(c))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# my settings\ndir "+dir+"\nsave 60 5\n\n# the port\nport 7000\n",
+		"comments stay and the save lines collapse into one")
+	assert.Contains(t, string(data), "# Generated by CONFIG REWRITE\n")
+
+	got, err := config.Load(path, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.RDB, got.RDB)
+	assert.Equal(t, c.Password, got.Password)
+	assert.True(t, got.AOFenabled)
+	assert.Equal(t, c.Users, got.Users)
+
+	// Rewriting again changes nothing.
+	require.NoError(t, config.Rewrite(got))
+	again, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+
+	assert.Error(t, config.Rewrite(config.NewConfig()), "there is no file to rewrite")
+}
+
+func TestConfigSetLive(t *testing.T) {
+	addr, state, _ := runServer(t)
+	conn := dial(t, addr)
+
+	reply := conn.send("CONFIG GET app*\r\n")
+	require.Len(t, reply.Array, 6)
+	assert.Equal(t, []string{"appendfilename", "appendfsync", "appendonly"},
+		[]string{reply.Array[0].String, reply.Array[2].String, reply.Array[4].String})
+
+	t.Run("parameters are set together", func(t *testing.T) {
+		reply := conn.send("CONFIG SET save 60 acllog-max-len 5\r\n")
+		assert.Equal(t, "ERR CONFIG SET failed (possibly related to argument 'save') - invalid save parameters", reply.String)
+		assert.Equal(t, "128", conn.send("CONFIG GET acllog-max-len\r\n").Array[1].String)
+
+		reply = conn.send("CONFIG SET acllog-max-len 5 port 1\r\n")
+		assert.Equal(t, "ERR CONFIG SET failed (possibly related to argument 'port') - can't set immutable config", reply.String)
+		assert.Equal(t, "128", conn.send("CONFIG GET acllog-max-len\r\n").Array[1].String)
+
+		require.Equal(t, resp.OK, conn.send("CONFIG SET save \"60 100\" acllog-max-len 5\r\n"))
+		assert.Equal(t, []config.RDBSnapshot{{Secs: 60, KeysChanged: 100}}, state.Saver.Rules())
+	})
+
+	t.Run("appendonly writes a base", func(t *testing.T) {
+		require.Equal(t, resp.OK, conn.send("SET before 1\r\n"))
+		require.Equal(t, int64(1), conn.send("EXPIRE before 100\r\n").Integer)
+		require.Equal(t, resp.OK, conn.send("CONFIG SET appendonly yes\r\n"))
+		require.Equal(t, resp.OK, conn.send("SET after 2\r\n"))
+		require.Equal(t, resp.OK, conn.send("CONFIG SET appendonly no\r\n"))
+
+		data, err := os.ReadFile(filepath.Join(state.Config.Dir, state.Config.AOFfn))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "$6\r\nbefore\r\n")
+		assert.Contains(t, string(data), "$9\r\nPEXPIREAT\r\n$6\r\nbefore\r\n")
+		assert.Contains(t, string(data), "$5\r\nafter\r\n")
+	})
+
+	t.Run("requirepass", func(t *testing.T) {
+		require.Equal(t, resp.OK, conn.send("CONFIG SET requirepass secret\r\n"))
+		other := dial(t, addr)
+		assert.Equal(t, "NOAUTH Authentication required.", other.send("PING\r\n").String)
+		assert.Equal(t, resp.OK, other.send("AUTH secret\r\n"))
+		require.Equal(t, resp.OK, other.send("CONFIG SET requirepass \"\"\r\n"))
+		assert.Equal(t, "PONG", dial(t, addr).send("PING\r\n").String)
+	})
+
+	t.Run("resetstat", func(t *testing.T) {
+		conn.send("GET missing\r\n")
+		assert.Contains(t, conn.send("INFO stats\r\n").String, "keyspace_misses:")
+		require.Equal(t, resp.OK, conn.send("CONFIG RESETSTAT\r\n"))
+		info := conn.send("INFO stats\r\n").String
+		assert.Contains(t, info, "keyspace_misses:0\r\n")
+		assert.Contains(t, info, "total_connections_received:0\r\n")
+	})
+
+	t.Run("rewrite needs a file", func(t *testing.T) {
+		assert.Equal(t, "ERR The server is running without a config file", conn.send("CONFIG REWRITE\r\n").String)
+	})
+}
@@ -0,0 +1,193 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotIsCopyOnWrite(t *testing.T) {
+	d := db.NewDatabase()
+	d.Set("a", "1")
+	d.Set("b", "2")
+	d.Set("c", "3")
+
+	frozen, err := d.BeginSnapshot()
+	require.NoError(t, err)
+
+	_, err = d.BeginSnapshot()
+	assert.ErrorIs(t, err, db.ErrSnapshotInProgress)
+
+	d.Set("a", "changed")
+	d.Del("b")
+	d.Set("d", "4")
+	assert.True(t, d.SetExpiry("c", time.Now().Add(time.Hour)))
+
+	// Live view sees the writes...
+	item, ok := d.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "changed", item.Value)
+	_, ok = d.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, 3, d.GetLen())
+
+	// ...while the snapshot stays at the point in time it was taken.
+	assert.Equal(t, 3, frozen.Len())
+	a, _ := frozen.Get("a")
+	assert.Equal(t, "1", a.Value)
+	b, _ := frozen.Get("b")
+	assert.Equal(t, "2", b.Value)
+	c, _ := frozen.Get("c")
+	assert.Equal(t, db.UNIX_TS_EPOCH, c.Expires.Unix())
+
+	d.EndSnapshot()
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, *d.GetKeys())
+	item, _ = d.Get("a")
+	assert.Equal(t, "changed", item.Value)
+}
+
+func TestSnapshotFlushWhileFrozen(t *testing.T) {
+	d := db.NewDatabase()
+	d.Set("a", "1")
+
+	frozen, err := d.BeginSnapshot()
+	require.NoError(t, err)
+
+	d.Reset()
+	d.Set("b", "2")
+	assert.Equal(t, 1, d.GetLen())
+	_, ok := d.Get("a")
+	assert.False(t, ok)
+	_, ok = frozen.Get("a")
+	assert.True(t, ok)
+
+	d.EndSnapshot()
+	assert.Equal(t, []string{"b"}, *d.GetKeys())
+}
+
+func TestKeySlot(t *testing.T) {
+	// Values from CLUSTER KEYSLOT.
+	assert.Equal(t, 12182, db.KeySlot("foo"))
+	assert.Equal(t, 12739, db.KeySlot("123456789"))
+	assert.Equal(t, db.KeySlot("{user1000}.following"), db.KeySlot("{user1000}.followers"))
+	// Only the first {...} counts, and not when it is empty.
+	assert.Equal(t, db.KeySlot("bar"), db.KeySlot("foo{bar}{zap}"))
+	assert.NotEqual(t, db.KeySlot("{}a"), db.KeySlot("{}b"))
+}
+
+func TestWithKeysLocksInOrder(t *testing.T) {
+	d := db.NewShardedDatabase(8)
+
+	// Opposite key orders would deadlock without a global lock order.
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				keys := []string{fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i), "c"}
+				if g%2 == 1 {
+					keys = []string{keys[2], keys[1], keys[0]}
+				}
+				d.WithKeys(keys, func(tx db.Tx) {
+					for _, k := range keys {
+						tx.Set(k, "v")
+					}
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1001, d.GetLen())
+}
+
+func TestScanReturnsStableKeysOnce(t *testing.T) {
+	d := db.NewShardedDatabase(8)
+	for i := range 1000 {
+		d.Set(fmt.Sprintf("stable:%d", i), "v")
+	}
+	d.Set("stable:a/b", "v")
+
+	seen := map[string]int{}
+	cursor, churn := 0, 0
+	for {
+		var keys []string
+		cursor, keys = d.Scan(cursor, 7)
+		for _, k := range keys {
+			seen[k]++
+		}
+
+		// Keys come and go between calls.
+		d.Set(fmt.Sprintf("churn:%d", churn), "v")
+		d.Del(fmt.Sprintf("churn:%d", churn-3))
+		churn++
+
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for i := range 1000 {
+		assert.Equal(t, 1, seen[fmt.Sprintf("stable:%d", i)], "stable:%d", i)
+	}
+	assert.Equal(t, 1, seen["stable:a/b"])
+}
+
+func TestDelCountsEachKeyOnce(t *testing.T) {
+	d := db.NewShardedDatabase(8)
+	for i := range 1000 {
+		d.Set(fmt.Sprintf("k%d", i), "v")
+	}
+
+	var deleted atomic.Int64
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				if d.Del(fmt.Sprintf("k%d", i)) {
+					deleted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), deleted.Load())
+}
+
+func TestGetKeysSeesMultiKeyWritesWhole(t *testing.T) {
+	d := db.NewShardedDatabase(8)
+	// Keys in different slots, moved back and forth as RENAME does.
+	names := [2]string{"{a}key", "{b}key"}
+	require.NotEqual(t, db.KeySlot(names[0])%8, db.KeySlot(names[1])%8)
+	d.Set(names[0], "v")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 2000 {
+			src, dst := names[i%2], names[(i+1)%2]
+			d.WithKeys([]string{src, dst}, func(tx db.Tx) {
+				item, _ := tx.Get(src)
+				tx.Del(src)
+				tx.Set(dst, item.Value)
+			})
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		require.Len(t, *d.GetKeys(), 1)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseInfo splits an INFO reply into its sections, each a map of fields.
+func parseInfo(t *testing.T, info string) map[string]map[string]string {
+	t.Helper()
+	sections := map[string]map[string]string{}
+	var cur map[string]string
+	for _, line := range strings.Split(strings.TrimSuffix(info, "\r\n"), "\r\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# "):
+			cur = map[string]string{}
+			sections[strings.ToLower(line[2:])] = cur
+		default:
+			name, val, ok := strings.Cut(line, ":")
+			require.True(t, ok, "malformed line %q", line)
+			require.NotNil(t, cur, "field %q outside a section", line)
+			cur[name] = val
+		}
+	}
+	return sections
+}
+
+func TestInfo(t *testing.T) {
+	db.DB.Reset()
+	t.Cleanup(db.DB.Reset)
+	addr, state, _ := runServer(t)
+	conn := dial(t, addr)
+
+	require.Equal(t, resp.OK, conn.send("SET a 1\r\n"))
+	require.Equal(t, resp.OK, conn.send("SET b 2\r\n"))
+	require.Equal(t, resp.OK, conn.send("SET c 3\r\n"))
+	require.Equal(t, int64(1), conn.send("EXPIRE b 100\r\n").Integer)
+	require.Equal(t, int64(1), conn.send("EXPIRE c 1\r\n").Integer)
+	conn.send("GET a\r\n")
+	conn.send("GET missing\r\n")
+
+	info := parseInfo(t, conn.send("INFO\r\n").String)
+	for _, name := range []string{"server", "clients", "memory", "persistence", "stats", "replication", "keyspace"} {
+		assert.Contains(t, info, name)
+	}
+	assert.Equal(t, db.RedisVersion, info["server"]["redis_version"])
+	assert.Equal(t, state.RunID, info["server"]["run_id"])
+	assert.Equal(t, "1", info["clients"]["connected_clients"])
+	assert.NotEqual(t, "0", info["memory"]["used_memory"])
+	assert.Equal(t, "0", info["persistence"]["aof_enabled"])
+	assert.Equal(t, "master", info["replication"]["role"])
+	assert.Equal(t, "1", info["stats"]["keyspace_hits"])
+	assert.Equal(t, "1", info["stats"]["keyspace_misses"])
+	assert.Equal(t, "keys=3,expires=2,avg_ttl=0", info["keyspace"]["db0"])
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.True(t, conn.send("GET c\r\n").IsNull)
+	info = parseInfo(t, conn.send("INFO stats keyspace\r\n").String)
+	assert.Len(t, info, 2)
+	assert.Equal(t, "1", info["stats"]["expired_keys"])
+	assert.Equal(t, "keys=2,expires=1,avg_ttl=0", info["keyspace"]["db0"])
+}
@@ -1,10 +1,21 @@
 package test
 
 import (
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/server"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,3 +40,392 @@ func TestPing(t *testing.T) {
 	t.Log(string(buffer[:n]))
 	t.Fail()
 }
+
+// startServer runs a server on a free port and returns its address once it
+// is accepting commands. opts may adjust the configuration first.
+func startServer(t testing.TB, opts ...func(*config.Config)) string {
+	t.Helper()
+	addr, _, _ := runServer(t, opts...)
+	return addr
+}
+
+// runServer is startServer that also returns the server state and the
+// channel Start's result is sent on.
+func runServer(t testing.TB, opts ...func(*config.Config)) (string, *db.AppState, <-chan error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	conf := config.NewConfig()
+	conf.Bind = []string{"127.0.0.1"}
+	conf.Port = l.Addr().(*net.TCPAddr).Port
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	for _, opt := range opts {
+		opt(conf)
+	}
+	state := db.NewAppState(conf)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.NewServer().Start(state)
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close() //nolint:errcheck // probe only
+		return !state.Loading.InProgress()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return addr, state, done
+}
+
+func TestPipelining(t *testing.T) {
+	addr := startServer(t)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck // OK for testing
+
+	const n = 1000
+	var batch strings.Builder
+	for i := range n {
+		k := fmt.Sprintf("pipe:%d", i)
+		fmt.Fprintf(&batch, "*3\r\n$3\r\nSET\r\n$%d\r\n%s\r\n$1\r\nv\r\n", len(k), k)
+	}
+	batch.WriteString("*2\r\n$3\r\nGET\r\n$8\r\npipe:999\r\n")
+
+	_, err = conn.Write([]byte(batch.String()))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	reader := resp.NewReader(conn)
+	for i := range n {
+		reply, err := reader.ReadValue()
+		require.NoError(t, err, "reply %d", i)
+		require.Equal(t, "OK", reply.String, "reply %d", i)
+	}
+
+	reply, err := reader.ReadValue()
+	require.NoError(t, err)
+	assert.Equal(t, &resp.Value{Type: resp.BulkString, String: "v"}, reply)
+}
+
+// testConn is a client connection that sends inline commands.
+type testConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *resp.Reader
+}
+
+func dial(t *testing.T, addr string) *testConn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck // OK for testing
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return &testConn{t: t, conn: conn, reader: resp.NewReader(conn)}
+}
+
+// send writes cmd and returns the next value read from the connection.
+func (tc *testConn) send(cmd string) *resp.Value {
+	tc.t.Helper()
+	_, err := tc.conn.Write([]byte(cmd))
+	require.NoError(tc.t, err)
+	return tc.read()
+}
+
+func (tc *testConn) read() *resp.Value {
+	tc.t.Helper()
+	reply, err := tc.reader.ReadValue()
+	require.NoError(tc.t, err)
+	return reply
+}
+
+func TestHelloSwitchesProtocol(t *testing.T) {
+	addr := startServer(t)
+
+	send := dial(t, addr).send
+
+	configGet := "*3\r\n$6\r\nCONFIG\r\n$3\r\nGET\r\n$4\r\nsave\r\n"
+
+	reply := send(configGet)
+	require.Equal(t, resp.Array, reply.Type)
+	assert.Len(t, reply.Array, 2)
+
+	reply = send("HELLO 3 SETNAME app\r\n")
+	require.Equal(t, resp.Map, reply.Type)
+	proto, _ := reply.Lookup("proto")
+	assert.Equal(t, &resp.Value{Type: resp.Integer, Integer: 3}, proto)
+	server, _ := reply.Lookup("server")
+	assert.Equal(t, "redis", server.String)
+
+	reply = send(configGet)
+	require.Equal(t, resp.Map, reply.Type)
+	_, ok := reply.Lookup("save")
+	assert.True(t, ok)
+
+	reply = send("HELLO 2\r\n")
+	require.Equal(t, resp.Array, reply.Type)
+	assert.Len(t, reply.Array, 14)
+
+	reply = send("HELLO 4\r\n")
+	assert.Equal(t, &resp.Value{Type: resp.SimpleError, String: "NOPROTO unsupported protocol version"}, reply)
+}
+
+func TestClientTracking(t *testing.T) {
+	addr := startServer(t)
+	writer := dial(t, addr)
+
+	invalidate := func(keys ...string) *resp.Value {
+		arr := &resp.Value{Type: resp.Array, Array: []*resp.Value{}}
+		for _, k := range keys {
+			arr.Array = append(arr.Array, &resp.Value{Type: resp.BulkString, String: k})
+		}
+		return arr
+	}
+
+	t.Run("default mode pushes invalidate once", func(t *testing.T) {
+		reader := dial(t, addr)
+		reader.send("HELLO 3\r\n")
+		assert.Equal(t, resp.OK, reader.send("CLIENT TRACKING ON\r\n"))
+		reader.send("GET cfg:a\r\n")
+
+		writer.send("SET cfg:a 1\r\n")
+		writer.send("SET cfg:a 2\r\n")
+
+		assert.Equal(t, &resp.Value{Type: resp.Push, Array: []*resp.Value{
+			{Type: resp.BulkString, String: "invalidate"},
+			invalidate("cfg:a"),
+		}}, reader.read())
+		// The key is no longer tracked, so the second SET sent nothing and
+		// the next value read is the PING reply.
+		assert.Equal(t, "PONG", reader.send("PING\r\n").String)
+	})
+
+	t.Run("noloop skips own writes", func(t *testing.T) {
+		reader := dial(t, addr)
+		reader.send("HELLO 3\r\n")
+		reader.send("CLIENT TRACKING ON BCAST NOLOOP\r\n")
+		reader.send("SET own 1\r\n")
+
+		writer.send("SET other 1\r\n")
+		push := reader.read()
+		require.Equal(t, resp.Push, push.Type)
+		assert.Equal(t, invalidate("other"), push.Array[1])
+	})
+
+	t.Run("optin only tracks after caching yes", func(t *testing.T) {
+		reader := dial(t, addr)
+		reader.send("HELLO 3\r\n")
+		reader.send("CLIENT TRACKING ON OPTIN\r\n")
+		reader.send("GET opt:untracked\r\n")
+		assert.Equal(t, resp.OK, reader.send("CLIENT CACHING YES\r\n"))
+		reader.send("GET opt:tracked\r\n")
+
+		writer.send("SET opt:untracked 1\r\n")
+		writer.send("SET opt:tracked 1\r\n")
+		push := reader.read()
+		require.Equal(t, resp.Push, push.Type)
+		assert.Equal(t, invalidate("opt:tracked"), push.Array[1])
+	})
+
+	t.Run("bcast redirect to resp2 subscriber", func(t *testing.T) {
+		sub := dial(t, addr)
+		id := sub.send("CLIENT ID\r\n").Integer
+		sub.send("SUBSCRIBE __redis__:invalidate\r\n")
+
+		tracker := dial(t, addr)
+		assert.Equal(t, resp.OK, tracker.send(fmt.Sprintf("CLIENT TRACKING ON REDIRECT %d BCAST PREFIX user:\r\n", id)))
+		assert.Equal(t, id, tracker.send("CLIENT GETREDIR\r\n").Integer)
+
+		writer.send("SET session:1 x\r\n")
+		writer.send("SET user:1 x\r\n")
+		assert.Equal(t, &resp.Value{Type: resp.Array, Array: []*resp.Value{
+			{Type: resp.BulkString, String: "message"},
+			{Type: resp.BulkString, String: "__redis__:invalidate"},
+			invalidate("user:1"),
+		}}, sub.read())
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		c := dial(t, addr)
+		assert.Equal(t, resp.SimpleError, c.send("CLIENT TRACKING ON PREFIX a\r\n").Type)
+		assert.Equal(t, resp.SimpleError, c.send("CLIENT TRACKING ON OPTIN OPTOUT\r\n").Type)
+		assert.Equal(t, resp.SimpleError, c.send("CLIENT TRACKING ON REDIRECT 999999\r\n").Type)
+		assert.Equal(t, resp.SimpleError, c.send("CLIENT CACHING YES\r\n").Type)
+	})
+}
+
+func TestMultiKeyCommands(t *testing.T) {
+	addr := startServer(t)
+	c := dial(t, addr)
+
+	assert.Equal(t, resp.OK, c.send("MSET {u}:a 1 {u}:b 2 other 3\r\n"))
+	assert.Equal(t, "2", c.send("GET {u}:b\r\n").String)
+
+	assert.Equal(t, resp.OK, c.send("RENAME {u}:a renamed\r\n"))
+	assert.True(t, c.send("GET {u}:a\r\n").IsNull)
+	assert.Equal(t, "1", c.send("GET renamed\r\n").String)
+	assert.Equal(t, resp.SimpleError, c.send("RENAME missing x\r\n").Type)
+	assert.Equal(t, resp.OK, c.send("SET {u}:a/b 4\r\n"))
+
+	var keys []string
+	cursor := "0"
+	for {
+		reply := c.send("SCAN " + cursor + " MATCH {u}* COUNT 100\r\n")
+		require.Len(t, reply.Array, 2)
+		for _, k := range reply.Array[1].Array {
+			keys = append(keys, k.String)
+		}
+		if cursor = reply.Array[0].String; cursor == "0" {
+			break
+		}
+	}
+	assert.ElementsMatch(t, []string{"{u}:b", "{u}:a/b"}, keys, "* matches /")
+	assert.ElementsMatch(t, []string{"{u}:b", "{u}:a/b"}, bulkStrings(c.send("KEYS {u}*\r\n")))
+	assert.Equal(t, []string{"{u}:b"}, bulkStrings(c.send("KEYS {u}:[ab\r\n")), "an unterminated class runs to the end")
+}
+
+func TestShutdown(t *testing.T) {
+	testShutdown(t)
+}
+
+// testShutdown checks that SHUTDOWN saves the dataset, closes every
+// connection and makes Start return.
+func testShutdown(t *testing.T, opts ...func(*config.Config)) {
+	withSavePoint := func(c *config.Config) {
+		c.RDB = []config.RDBSnapshot{{Secs: 3600, KeysChanged: 1}}
+	}
+	addr, state, done := runServer(t, append(opts, withSavePoint)...)
+
+	c := dial(t, addr)
+	idle := dial(t, addr)
+	assert.Equal(t, resp.SimpleError, c.send("SHUTDOWN SAVE NOSAVE\r\n").Type)
+	assert.Equal(t, "ERR No shutdown in progress.", c.send("SHUTDOWN ABORT\r\n").String)
+	assert.Equal(t, resp.OK, c.send("SET shutdown:key v\r\n"))
+
+	_, err := c.conn.Write([]byte("SHUTDOWN\r\n"))
+	require.NoError(t, err)
+	_, err = c.reader.ReadValue()
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = idle.reader.ReadValue()
+	assert.Error(t, err)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	assert.FileExists(t, filepath.Join(state.Config.Dir, state.Config.RDBfn))
+	assert.Zero(t, state.RDBStatus.Snapshot().Dirty)
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+}
+
+func TestLoadAOF(t *testing.T) {
+	withAOF := func(content string) func(*config.Config) {
+		return func(c *config.Config) {
+			c.AOFenabled = true
+			c.RDB = []config.RDBSnapshot{{Secs: 3600, KeysChanged: 1}}
+			require.NoError(t, os.WriteFile(filepath.Join(c.Dir, c.AOFfn), []byte(content), 0644))
+		}
+	}
+	set := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+
+	t.Run("truncated tail", func(t *testing.T) {
+		db.DB.Reset()
+		defer db.DB.Reset()
+		addr, state, _ := runServer(t, withAOF(set+"*3\r\n$3\r\nSET\r\n$1\r\nb"))
+
+		c := dial(t, addr)
+		assert.Equal(t, "1", c.send("GET a\r\n").String)
+		assert.True(t, c.send("GET b\r\n").IsNull)
+		// Replaying is not a change to save.
+		assert.Zero(t, state.RDBStatus.Snapshot().Dirty)
+	})
+
+	t.Run("expiry is logged as an absolute time", func(t *testing.T) {
+		db.DB.Reset()
+		defer db.DB.Reset()
+		pexpireat := func(key string, at time.Time) string {
+			ms := strconv.FormatInt(at.UnixMilli(), 10)
+			return fmt.Sprintf("*3\r\n$9\r\nPEXPIREAT\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(key), key, len(ms), ms)
+		}
+		content := set + pexpireat("a", time.Now().Add(-time.Second)) +
+			"*3\r\n$3\r\nSET\r\n$1\r\nb\r\n$1\r\n2\r\n" + pexpireat("b", time.Now().Add(time.Hour))
+		addr, state, _ := runServer(t, withAOF(content))
+
+		c := dial(t, addr)
+		assert.True(t, c.send("GET a\r\n").IsNull, "expired while the server was down")
+		assert.InDelta(t, 3600, c.send("TTL b\r\n").Integer, 2)
+
+		require.Equal(t, int64(1), c.send("EXPIRE b 100\r\n").Integer)
+		require.NoError(t, state.Aof.Flush())
+		data, err := os.ReadFile(filepath.Join(state.Config.Dir, state.Config.AOFfn))
+		require.NoError(t, err)
+		// EXPIRE is appended as PEXPIREAT with a timestamp in milliseconds.
+		assert.Contains(t, string(data[len(content):]), "$9\r\nPEXPIREAT\r\n$1\r\nb\r\n$13\r\n")
+	})
+
+	t.Run("corrupt command", func(t *testing.T) {
+		db.DB.Reset()
+		defer db.DB.Reset()
+		conf := config.NewConfig()
+		conf.Bind = []string{"127.0.0.1"}
+		conf.Port = freePort(t)
+		conf.Dir = t.TempDir()
+		withAOF(set + "garbage\r\n" + set)(conf)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- server.NewServer().Start(db.NewAppState(conf))
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorContains(t, err, "after 1 commands")
+		case <-time.After(5 * time.Second):
+			t.Fatal("server started from a corrupt AOF")
+		}
+	})
+}
+
+func TestSlowSubscriberDoesNotBlockPublish(t *testing.T) {
+	addr, state, _ := runServer(t)
+
+	// The subscriber never reads, so its socket buffers fill up.
+	sub := dial(t, addr)
+	sub.send("SUBSCRIBE news\r\n")
+
+	pub := dial(t, addr)
+	payload := strings.Repeat("x", 1024)
+	const n = 48 * 1024
+	go func() {
+		var batch strings.Builder
+		for range n {
+			fmt.Fprintf(&batch, "PUBLISH news %s\r\n", payload)
+		}
+		_, _ = pub.conn.Write([]byte(batch.String()))
+	}()
+
+	// Every PUBLISH is answered even though the subscriber is stuck.
+	require.NoError(t, pub.conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	for i := range n {
+		reply, err := pub.reader.ReadValue()
+		require.NoError(t, err, "reply %d", i)
+		require.Equal(t, resp.Integer, reply.Type)
+	}
+
+	// Falling that far behind gets the subscriber disconnected.
+	assert.Eventually(t, func() bool { return state.PubSub.NumClients() == 0 },
+		5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/rdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRDBRoundTrip(t *testing.T) {
+	expireAt := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	entries := []rdb.Entry{
+		{Key: "plain", Value: "hello"},
+		{Key: "int8", Value: "-12"},
+		{Key: "int16", Value: "3000"},
+		{Key: "int32", Value: "-2000000000"},
+		{Key: "not-canonical", Value: "007"},
+		{Key: "compressed", Value: strings.Repeat("abcdefgh", 200)},
+		{Key: "long", Value: strings.Repeat("x", 20000)},
+		{Key: "empty", Value: ""},
+		{Key: "volatile", Value: "bye", ExpireAt: expireAt},
+	}
+
+	var buf bytes.Buffer
+	enc := rdb.NewEncoder(&buf, rdb.EncoderOptions{Compress: true, Checksum: true})
+	require.NoError(t, enc.WriteHeader())
+	require.NoError(t, enc.WriteAux("redis-ver", "7.2.0"))
+	require.NoError(t, enc.WriteSelectDB(0))
+	require.NoError(t, enc.WriteResizeDB(len(entries), 1))
+	for _, e := range entries {
+		require.NoError(t, enc.WriteString(e.Key, e.Value, e.ExpireAt))
+	}
+	require.NoError(t, enc.WriteEOF())
+
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("REDIS0011")))
+	assert.Less(t, buf.Len(), 20000+len(strings.Repeat("abcdefgh", 200)), "expected compressed output")
+
+	var got []rdb.Entry
+	dec := rdb.NewDecoder(&buf)
+	require.NoError(t, dec.Decode(func(e *rdb.Entry) error {
+		got = append(got, *e)
+		return nil
+	}))
+
+	assert.Equal(t, rdb.Version, dec.Version)
+	assert.Equal(t, "7.2.0", dec.Aux["redis-ver"])
+	assert.Equal(t, dec.Computed, dec.Checksum)
+	require.Len(t, got, len(entries))
+	for i, e := range entries {
+		assert.Equal(t, e.Key, got[i].Key)
+		assert.Equal(t, e.Value, got[i].Value)
+		assert.Equal(t, rdb.TypeString, got[i].Type)
+		assert.True(t, e.ExpireAt.Equal(got[i].ExpireAt), "expiry of %s", e.Key)
+	}
+}
+
+func TestRDBChecksum(t *testing.T) {
+	assert.Equal(t, uint64(0xe9c6d914c4b8d9ca), rdb.Checksum([]byte("123456789")))
+}
+
+func TestRDBRejectsBadHeader(t *testing.T) {
+	err := rdb.NewDecoder(strings.NewReader("RODIS0011\xff")).Decode(func(*rdb.Entry) error { return nil })
+	assert.ErrorIs(t, err, rdb.ErrBadMagic)
+
+	err = rdb.NewDecoder(strings.NewReader("REDIS0099\xff")).Decode(func(*rdb.Entry) error { return nil })
+	assert.ErrorIs(t, err, rdb.ErrBadVersion)
+}
+
+func TestRDBRejectsHugeLength(t *testing.T) {
+	// A string key whose 32-bit length prefix claims ~4 GiB.
+	input := "REDIS0011\x00\x80\xff\xff\xff\xf0"
+	err := rdb.NewDecoder(strings.NewReader(input)).Decode(func(*rdb.Entry) error { return nil })
+	assert.ErrorContains(t, err, "too large")
+
+	dec := rdb.NewDecoder(strings.NewReader("REDIS0011\x00\x80\x00\x10\x00\x00"))
+	dec.MaxStringLen = 1 << 30
+	err = dec.Decode(func(*rdb.Entry) error { return nil })
+	assert.ErrorContains(t, err, "EOF")
+}
+
+func TestSyncRDBRejectsUnsupportedTypes(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	state := db.NewAppState(conf)
+
+	// A list "l" holding "a", then a string "s"; checksum disabled.
+	data := "REDIS0011\x01\x01l\x01\x01a\x00\x01s\x01v\xff" + strings.Repeat("\x00", 8)
+	require.NoError(t, os.WriteFile(filepath.Join(conf.Dir, "dump.rdb"), []byte(data), 0644))
+
+	db.DB.Reset()
+	defer db.DB.Reset()
+	assert.ErrorIs(t, db.SyncRDB(state), db.ErrUnsupportedType)
+	assert.Equal(t, 0, db.DB.GetLen())
+}
+
+func TestSyncRDBRejectsOtherDatabases(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	state := db.NewAppState(conf)
+
+	var buf bytes.Buffer
+	enc := rdb.NewEncoder(&buf, rdb.EncoderOptions{Checksum: true})
+	require.NoError(t, enc.WriteHeader())
+	for _, n := range []int{0, 3} {
+		require.NoError(t, enc.WriteSelectDB(n))
+		require.NoError(t, enc.WriteResizeDB(1, 0))
+		require.NoError(t, enc.WriteString("k", "in db "+strconv.Itoa(n), time.Time{}))
+	}
+	require.NoError(t, enc.WriteEOF())
+	require.NoError(t, os.WriteFile(filepath.Join(conf.Dir, "dump.rdb"), buf.Bytes(), 0644))
+
+	db.DB.Reset()
+	defer db.DB.Reset()
+	assert.ErrorIs(t, db.SyncRDB(state), db.ErrUnsupportedDB)
+	assert.Equal(t, 0, db.DB.GetLen(), "db 3's key does not overwrite db 0's")
+}
+
+func TestSaveRDBReplacesFileAtomically(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	state := db.NewAppState(conf)
+
+	db.DB.Reset()
+	defer db.DB.Reset()
+	db.DB.Set("k", "v")
+
+	require.NoError(t, db.SaveRDB(state))
+
+	files, err := os.ReadDir(conf.Dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "temp file left behind")
+	assert.Equal(t, "dump.rdb", files[0].Name())
+	assert.True(t, state.RDBStatus.Snapshot().LastOK)
+
+	// Flip a byte in the payload: the embedded checksum must reject the file.
+	fp := filepath.Join(conf.Dir, "dump.rdb")
+	data, err := os.ReadFile(fp)
+	require.NoError(t, err)
+	data[len(data)-12] ^= 0xFF
+	require.NoError(t, os.WriteFile(fp, data, 0644))
+
+	db.DB.Reset()
+	assert.ErrorContains(t, db.SyncRDB(state), "checksum")
+	assert.Equal(t, 0, db.DB.GetLen())
+}
+
+func TestSaveSchedulerHonorsEveryRule(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	conf.RDB = []config.RDBSnapshot{{Secs: 900, KeysChanged: 1}, {Secs: 1, KeysChanged: 3}}
+	state := db.NewAppState(conf)
+	state.Saver.Start()
+	defer state.Saver.Stop()
+
+	// One change satisfies neither "900 1" (too early) nor "1 3" (too few).
+	state.RDBStatus.AddDirty(1)
+	time.Sleep(1200 * time.Millisecond)
+	assert.Equal(t, int64(0), state.RDBStatus.Snapshot().Saves)
+
+	state.RDBStatus.AddDirty(2)
+	assert.Eventually(t, func() bool {
+		s := state.RDBStatus.Snapshot()
+		return s.Saves == 1 && s.Dirty == 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// Rules can be replaced at runtime; none means no more saves.
+	state.Saver.SetRules(nil)
+	state.RDBStatus.AddDirty(10)
+	time.Sleep(1200 * time.Millisecond)
+	assert.Equal(t, int64(1), state.RDBStatus.Snapshot().Saves)
+}
@@ -0,0 +1,188 @@
+//go:build linux
+
+package test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withIOModel(model config.IOModel, threads int) func(*config.Config) {
+	return func(c *config.Config) {
+		c.IOModel = model
+		c.IOThreads = threads
+	}
+}
+
+func TestReactorMode(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		t.Run(fmt.Sprintf("io-threads %d", threads), func(t *testing.T) {
+			addr := startServer(t, withIOModel(config.ReactorIO, threads))
+
+			t.Run("pipelined and split requests", func(t *testing.T) {
+				c := dial(t, addr)
+				var batch strings.Builder
+				for i := range 200 {
+					fmt.Fprintf(&batch, "*3\r\n$3\r\nSET\r\n$5\r\nk%04d\r\n$1\r\nv\r\n", i)
+				}
+				batch.WriteString("PING\r\n\r\n*2\r\n$3\r\nGET\r\n$5\r\nk0199\r\n")
+
+				// Send the batch in small pieces so requests straddle reads.
+				data := []byte(batch.String())
+				for len(data) > 0 {
+					n := min(len(data), 7)
+					_, err := c.conn.Write(data[:n])
+					require.NoError(t, err)
+					data = data[n:]
+				}
+
+				for i := range 200 {
+					require.Equal(t, resp.OK, c.read(), "reply %d", i)
+				}
+				assert.Equal(t, "PONG", c.read().String)
+				assert.Equal(t, "v", c.read().String)
+			})
+
+			t.Run("large request over many reads", func(t *testing.T) {
+				c := dial(t, addr)
+				value := strings.Repeat("v", 4<<20)
+				req := fmt.Sprintf("*3\r\n$3\r\nSET\r\n$3\r\nbig\r\n$%d\r\n%s\r\n", len(value), value)
+				for data := []byte(req); len(data) > 0; {
+					n := min(len(data), 16*1024)
+					_, err := c.conn.Write(data[:n])
+					require.NoError(t, err)
+					data = data[n:]
+				}
+				require.Equal(t, resp.OK, c.read())
+				assert.Equal(t, len(value), len(c.send("GET big\r\n").String))
+			})
+
+			t.Run("push between connections", func(t *testing.T) {
+				sub := dial(t, addr)
+				sub.send("SUBSCRIBE news\r\n")
+
+				pub := dial(t, addr)
+				assert.Equal(t, int64(1), pub.send("PUBLISH news hello\r\n").Integer)
+				msg := sub.read()
+				require.Len(t, msg.Array, 3)
+				assert.Equal(t, "hello", msg.Array[2].String)
+			})
+
+			t.Run("quit drops pipelined commands", func(t *testing.T) {
+				c := dial(t, addr)
+				_, err := c.conn.Write([]byte("PING\r\nQUIT\r\nSET after quit\r\n"))
+				require.NoError(t, err)
+				assert.Equal(t, "PONG", c.read().String)
+				assert.Equal(t, resp.OK, c.read())
+				_, err = c.reader.ReadValue()
+				assert.Error(t, err)
+			})
+
+			t.Run("protocol error closes connection", func(t *testing.T) {
+				c := dial(t, addr)
+				reply := c.send("*1\r\n$999999999999\r\n")
+				assert.Equal(t, resp.SimpleError, reply.Type)
+				_, err := c.reader.ReadValue()
+				assert.Error(t, err)
+			})
+		})
+	}
+}
+
+func TestReactorShutdown(t *testing.T) {
+	testShutdown(t, withIOModel(config.ReactorIO, 1))
+}
+
+// benchConns is how many connections the mode benchmark opens: 10k, or as
+// many as the descriptor limit allows with both ends in this process.
+func benchConns(b *testing.B) int {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim); err == nil && lim.Cur < lim.Max {
+		lim.Cur = lim.Max
+		_ = syscall.Setrlimit(syscall.RLIMIT_NOFILE, &lim)
+		_ = syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim)
+	}
+	return int(min(10000, (int64(lim.Cur)-512)/2))
+}
+
+// BenchmarkServerModes compares goroutine-per-connection and reactor mode
+// with 10k open connections. Workers each own a slice of the connections
+// and run SET/GET round trips across them in turn.
+func BenchmarkServerModes(b *testing.B) {
+	modes := []struct {
+		name    string
+		model   config.IOModel
+		threads int
+	}{
+		{"goroutine", config.GoroutineIO, 1},
+		{"reactor", config.ReactorIO, 1},
+		{"reactor-iothreads", config.ReactorIO, 4},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			addr := startServer(b, withIOModel(mode.model, mode.threads))
+
+			n := benchConns(b)
+			conns := make([]net.Conn, 0, n)
+			readers := make([]*bufio.Reader, 0, n)
+			for range n {
+				conn, err := net.Dial("tcp", addr)
+				require.NoError(b, err)
+				conns = append(conns, conn)
+				readers = append(readers, bufio.NewReader(conn))
+			}
+			defer func() {
+				for _, conn := range conns {
+					conn.Close() //nolint:errcheck // OK for testing
+				}
+			}()
+
+			const workers = 64
+			cmd := []byte("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$3\r\nval\r\n")
+
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			for w := range workers {
+				ops := b.N / workers
+				if w < b.N%workers {
+					ops++
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range ops {
+						k := (w + i*workers) % n
+						if _, err := conns[k].Write(cmd); err != nil {
+							b.Error(err)
+							return
+						}
+						if _, err := readers[k].ReadString('\n'); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			b.StopTimer()
+			b.ReportMetric(float64(n), "conns")
+
+			// Let the server notice the closed connections before the next
+			// mode starts.
+			time.Sleep(100 * time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"testing"
+
+	myio "github.com/shivakuppa/Go_Redis/internals/io"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// legacySerialize is the string-concatenating serializer that RespWriter
+// used before AppendValue, kept here as the baseline for the benchmarks.
+func legacySerialize(v *resp.Value) string {
+	switch v.Type {
+	case resp.SimpleString:
+		return "+" + v.String + "\r\n"
+	case resp.Integer:
+		return ":" + strconv.Itoa(int(v.Integer)) + "\r\n"
+	case resp.BulkString:
+		if v.IsNull {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(v.String)) + "\r\n" + v.String + "\r\n"
+	case resp.Array:
+		var serialized string
+		for _, elem := range v.Array {
+			serialized += legacySerialize(elem)
+		}
+		return "*" + strconv.Itoa(len(v.Array)) + "\r\n" + serialized
+	}
+	return ""
+}
+
+// lrangeReply builds an LRANGE-style reply of n short bulk strings.
+func lrangeReply(n int) *resp.Value {
+	reply := &resp.Value{Type: resp.Array, Array: make([]*resp.Value, n)}
+	for i := range n {
+		reply.Array[i] = &resp.Value{Type: resp.BulkString, String: "element:" + strconv.Itoa(i)}
+	}
+	return reply
+}
+
+var benchReplies = []struct {
+	name  string
+	value *resp.Value
+}{
+	{"OK", resp.OK},
+	{"Integer", &resp.Value{Type: resp.Integer, Integer: 42}},
+	{"Bulk", &resp.Value{Type: resp.BulkString, String: "hello world"}},
+	{"LRANGE100", lrangeReply(100)},
+	{"LRANGE10000", lrangeReply(10000)},
+}
+
+func BenchmarkLegacyWrite(b *testing.B) {
+	for _, bc := range benchReplies {
+		b.Run(bc.name, func(b *testing.B) {
+			w := bufio.NewWriter(io.Discard)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := w.Write([]byte(legacySerialize(bc.value))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRespWriter(b *testing.B) {
+	for _, bc := range benchReplies {
+		b.Run(bc.name, func(b *testing.B) {
+			w := myio.NewRespWriter(io.Discard)
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := w.Write(bc.value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRespWriterMatchesLegacy(t *testing.T) {
+	for _, bc := range benchReplies {
+		encoded, err := resp.Serialize(bc.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != legacySerialize(bc.value) {
+			t.Errorf("%s: encoding differs from the legacy serializer", bc.name)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+)
+
+// FuzzDeserialize checks that arbitrary input never panics and that every
+// value the parser accepts can be encoded again. Seeds live in
+// testdata/fuzz/FuzzDeserialize.
+func FuzzDeserialize(f *testing.F) {
+	f.Add("*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n")
+	f.Add("%1\r\n+k\r\n,1.5\r\n")
+	f.Add("$-1\r\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		value, err := resp.Deserialize(strings.NewReader(input))
+		if err != nil {
+			return
+		}
+		if _, err := resp.AppendValue(nil, value); err != nil {
+			t.Fatalf("accepted %q but cannot encode it: %v", input, err)
+		}
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/splitargs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDeserializeRESP3(t *testing.T) {
@@ -119,7 +121,7 @@ func TestDeserializeRESP3(t *testing.T) {
 		},
 		{
 			name:  "bulk error",
-			input: "!21\r\nSYNTAX invalid input\r\n",
+			input: "!20\r\nSYNTAX invalid input\r\n",
 			expectedVal: &resp.Value{
 				Type:   resp.BulkError,
 				String: "SYNTAX invalid input",
@@ -127,7 +129,7 @@ func TestDeserializeRESP3(t *testing.T) {
 		},
 		{
 			name:  "verbatim string",
-			input: "=15\r\ntxt:Some text\r\n",
+			input: "=13\r\ntxt:Some text\r\n",
 			expectedVal: &resp.Value{
 				Type:   resp.VerbatimString,
 				String: "txt:Some text",
@@ -138,9 +140,9 @@ func TestDeserializeRESP3(t *testing.T) {
 			input: "~2\r\n+apple\r\n+banana\r\n",
 			expectedVal: &resp.Value{
 				Type: resp.Set,
-				Set: map[*resp.Value]struct{}{
-					{Type: resp.SimpleString, String: "apple"}:  {},
-					{Type: resp.SimpleString, String: "banana"}: {},
+				Set: []*resp.Value{
+					{Type: resp.SimpleString, String: "apple"},
+					{Type: resp.SimpleString, String: "banana"},
 				},
 			},
 		},
@@ -149,9 +151,9 @@ func TestDeserializeRESP3(t *testing.T) {
 			input: "%2\r\n+key1\r\n+value1\r\n+key2\r\n+value2\r\n",
 			expectedVal: &resp.Value{
 				Type: resp.Map,
-				Map: map[string]*resp.Value{
-					"key1": {Type: resp.SimpleString, String: "value1"},
-					"key2": {Type: resp.SimpleString, String: "value2"},
+				Map: []resp.MapEntry{
+					{Key: &resp.Value{Type: resp.SimpleString, String: "key1"}, Value: &resp.Value{Type: resp.SimpleString, String: "value1"}},
+					{Key: &resp.Value{Type: resp.SimpleString, String: "key2"}, Value: &resp.Value{Type: resp.SimpleString, String: "value2"}},
 				},
 			},
 		},
@@ -278,15 +280,15 @@ func TestSerializeRESP3(t *testing.T) {
 				Type:   resp.VerbatimString,
 				String: "txt:Some text",
 			},
-			expectedSerialized: "=15\r\ntxt:Some text\r\n",
+			expectedSerialized: "=13\r\ntxt:Some text\r\n",
 		},
 		{
 			name: "map",
 			value: &resp.Value{
 				Type: resp.Map,
-				Map: map[string]*resp.Value{
-					"key1": {Type: resp.SimpleString, String: "value1"},
-					"key2": {Type: resp.SimpleString, String: "value2"},
+				Map: []resp.MapEntry{
+					{Key: &resp.Value{Type: resp.SimpleString, String: "key1"}, Value: &resp.Value{Type: resp.SimpleString, String: "value1"}},
+					{Key: &resp.Value{Type: resp.SimpleString, String: "key2"}, Value: &resp.Value{Type: resp.SimpleString, String: "value2"}},
 				},
 			},
 			expectedSerialized: "%2\r\n+key1\r\n+value1\r\n+key2\r\n+value2\r\n",
@@ -295,12 +297,11 @@ func TestSerializeRESP3(t *testing.T) {
 			name: "set",
 			value: &resp.Value{
 				Type: resp.Set,
-				Set: map[*resp.Value]struct{}{
-					{Type: resp.SimpleString, String: "apple"}:  {},
-					{Type: resp.SimpleString, String: "banana"}: {},
+				Set: []*resp.Value{
+					{Type: resp.SimpleString, String: "apple"},
+					{Type: resp.SimpleString, String: "banana"},
 				},
 			},
-			// Order may vary; you might use custom set comparison in Deserialize
 			expectedSerialized: "~2\r\n+apple\r\n+banana\r\n",
 		},
 		{
@@ -339,3 +340,247 @@ func TestSerializeRESP3(t *testing.T) {
 		})
 	}
 }
+
+func TestReaderKeepsPipelinedBytes(t *testing.T) {
+	reader := resp.NewReader(strings.NewReader("*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\n"))
+
+	first, err := reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, "PING", first.Array[0].String)
+	assert.NotZero(t, reader.Buffered())
+
+	second, err := reader.ReadValue()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ECHO", "hi"}, []string{second.Array[0].String, second.Array[1].String})
+	assert.Zero(t, reader.Buffered())
+}
+
+func TestSplitArgs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expected    []string
+		expectedErr bool
+	}{
+		{name: "plain", input: "SET key value", expected: []string{"SET", "key", "value"}},
+		{name: "extra whitespace", input: "  GET\t key  ", expected: []string{"GET", "key"}},
+		{name: "empty", input: "", expected: []string{}},
+		{name: "double quotes", input: `SET k "hello world"`, expected: []string{"SET", "k", "hello world"}},
+		{name: "escapes", input: `ECHO "a\nb\t\"c\"\\ \x41"`, expected: []string{"ECHO", "a\nb\t\"c\"\\ A"}},
+		{name: "single quotes", input: `ECHO 'it\'s "raw" \n'`, expected: []string{"ECHO", `it's "raw" \n`}},
+		{name: "empty quoted", input: `SET k ""`, expected: []string{"SET", "k", ""}},
+		{name: "unterminated", input: `ECHO "abc`, expectedErr: true},
+		{name: "quote not followed by space", input: `ECHO "abc"def`, expectedErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := splitargs.Split(tc.input)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, args)
+		})
+	}
+}
+
+func TestReadCommandInline(t *testing.T) {
+	reader := resp.NewReader(strings.NewReader("PING\r\n\r\nSET k \"a b\"\n*1\r\n$4\r\nPING\r\nECHO \"oops\r\n"))
+
+	for _, expected := range [][]string{{"PING"}, {"SET", "k", "a b"}, {"PING"}} {
+		cmd, err := reader.ReadCommand()
+		assert.NoError(t, err)
+
+		var args []string
+		for _, v := range cmd.Array {
+			assert.Equal(t, resp.BulkString, v.Type)
+			args = append(args, v.String)
+		}
+		assert.Equal(t, expected, args)
+	}
+
+	_, err := reader.ReadCommand()
+	var protoErr *resp.ProtocolError
+	assert.ErrorAs(t, err, &protoErr)
+}
+
+func TestReaderLimits(t *testing.T) {
+	limits := resp.Limits{MaxBulkLen: 16, MaxMultibulkLen: 4, MaxDepth: 3}
+
+	testCases := []struct {
+		name  string
+		input string
+		ok    bool
+	}{
+		{name: "bulk within limit", input: "$5\r\nhello\r\n", ok: true},
+		{name: "bulk too long", input: "$999999999\r\n", ok: false},
+		{name: "negative bulk", input: "$-2\r\n", ok: false},
+		{name: "bulk error too long", input: "!17\r\n", ok: false},
+		{name: "array within limit", input: "*2\r\n:1\r\n:2\r\n", ok: true},
+		{name: "array too long", input: "*2147483647\r\n", ok: false},
+		{name: "map counts pairs", input: "%3\r\n", ok: false},
+		{name: "nesting within limit", input: "*1\r\n*1\r\n*1\r\n:1\r\n", ok: true},
+		{name: "nesting too deep", input: "*1\r\n*1\r\n*1\r\n*1\r\n:1\r\n", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := resp.NewReader(strings.NewReader(tc.input))
+			reader.SetLimits(limits)
+
+			_, err := reader.ReadValue()
+			if tc.ok {
+				assert.NoError(t, err)
+				return
+			}
+			var protoErr *resp.ProtocolError
+			assert.ErrorAs(t, err, &protoErr)
+		})
+	}
+}
+
+func TestToRESP2(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    *resp.Value
+		expected *resp.Value
+	}{
+		{
+			name:     "bulk string unchanged",
+			input:    &resp.Value{Type: resp.BulkString, String: "a"},
+			expected: &resp.Value{Type: resp.BulkString, String: "a"},
+		},
+		{
+			name:     "double",
+			input:    &resp.Value{Type: resp.Double, Double: 1.5},
+			expected: &resp.Value{Type: resp.BulkString, String: "1.5"},
+		},
+		{
+			name:     "boolean",
+			input:    &resp.Value{Type: resp.Boolean, Bool: true},
+			expected: &resp.Value{Type: resp.Integer, Integer: 1},
+		},
+		{
+			name:     "null",
+			input:    &resp.Value{Type: resp.Null, IsNull: true},
+			expected: &resp.Value{Type: resp.BulkString, IsNull: true},
+		},
+		{
+			name:     "bulk error",
+			input:    &resp.Value{Type: resp.BulkError, String: "ERR x"},
+			expected: &resp.Value{Type: resp.SimpleError, String: "ERR x"},
+		},
+		{
+			name:     "verbatim",
+			input:    &resp.Value{Type: resp.VerbatimString, String: "txt:hi"},
+			expected: &resp.Value{Type: resp.BulkString, String: "hi"},
+		},
+		{
+			name: "map",
+			input: &resp.Value{Type: resp.Map, Map: []resp.MapEntry{
+				{Key: &resp.Value{Type: resp.BulkString, String: "k"}, Value: &resp.Value{Type: resp.Boolean, Bool: false}},
+			}},
+			expected: &resp.Value{Type: resp.Array, Array: []*resp.Value{
+				{Type: resp.BulkString, String: "k"},
+				{Type: resp.Integer, Integer: 0},
+			}},
+		},
+		{
+			name: "nested array",
+			input: &resp.Value{Type: resp.Array, Array: []*resp.Value{
+				{Type: resp.Integer, Integer: 7},
+				{Type: resp.Attribute, Map: []resp.MapEntry{}},
+				{Type: resp.Double, Double: 2},
+			}},
+			expected: &resp.Value{Type: resp.Array, Array: []*resp.Value{
+				{Type: resp.Integer, Integer: 7},
+				{Type: resp.BulkString, String: "2"},
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, resp.ToRESP2(tc.input))
+		})
+	}
+}
+
+func TestRoundTripRESP3(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{name: "integer keys", input: "%2\r\n:1\r\n+one\r\n:2\r\n+two\r\n"},
+		{name: "map keeps wire order", input: "%3\r\n+z\r\n:1\r\n+a\r\n:2\r\n+m\r\n:3\r\n"},
+		{name: "nested aggregates", input: "*2\r\n%1\r\n+k\r\n~2\r\n:1\r\n#t\r\n>1\r\n*1\r\n,1.5\r\n"},
+		{name: "aggregate map key", input: "%1\r\n*2\r\n:1\r\n:2\r\n$5\r\npoint\r\n"},
+		{name: "attribute", input: "*2\r\n|1\r\n+ttl\r\n:3600\r\n$3\r\nval\r\n"},
+		{name: "null aggregates", input: "*3\r\n%-1\r\n~-1\r\n*-1\r\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			val, err := resp.Deserialize(strings.NewReader(tc.input))
+			require.NoError(t, err)
+
+			encoded, err := resp.Serialize(val)
+			require.NoError(t, err)
+			assert.Equal(t, tc.input, encoded)
+
+			again, err := resp.Deserialize(strings.NewReader(encoded))
+			require.NoError(t, err)
+			assert.True(t, val.Equal(again))
+		})
+	}
+}
+
+func TestSetMembersComparedByValue(t *testing.T) {
+	val, err := resp.Deserialize(strings.NewReader("~3\r\n+a\r\n*1\r\n:1\r\n+a\r\n"))
+	require.NoError(t, err)
+	assert.Len(t, val.Set, 2)
+	assert.True(t, val.Contains(&resp.Value{Type: resp.Array, Array: []*resp.Value{{Type: resp.Integer, Integer: 1}}}))
+
+	set := &resp.Value{Type: resp.Set}
+	assert.True(t, set.AddMember(&resp.Value{Type: resp.BulkString, String: "x"}))
+	assert.False(t, set.AddMember(&resp.Value{Type: resp.BulkString, String: "x"}))
+
+	reordered := &resp.Value{Type: resp.Set, Set: []*resp.Value{val.Set[1], val.Set[0]}}
+	assert.True(t, val.Equal(reordered))
+	// The two nested sets hold the same members in a different order.
+	nested, err := resp.Deserialize(strings.NewReader("~2\r\n~2\r\n:1\r\n:2\r\n~2\r\n:2\r\n:1\r\n"))
+	require.NoError(t, err)
+	require.Len(t, nested.Set, 1)
+	assert.Len(t, nested.Set[0].Set, 2)
+}
+
+func TestFrameLen(t *testing.T) {
+	limits := resp.DefaultLimits
+	testCases := []struct {
+		name       string
+		input      string
+		n, minNeed int
+	}{
+		{name: "complete", input: "*1\r\n$4\r\nPING\r\n", n: 14},
+		{name: "inline", input: "PING\r\nPI", n: 6},
+		{name: "partial inline", input: "PIN", minNeed: 4},
+		{name: "partial header", input: "*2\r\n$3", minNeed: 7},
+		// The bulk header says exactly where the request can end.
+		{name: "partial bulk", input: "*1\r\n$10\r\nab", minNeed: 21},
+		// Each missing element needs at least two more bytes.
+		{name: "missing elements", input: "*3\r\n$1\r\na\r\n", minNeed: 15},
+		{name: "invalid", input: "*1\r\n$x\r\n", n: -1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, need := resp.FrameLen([]byte(tc.input), limits)
+			assert.Equal(t, tc.n, n)
+			if tc.n == 0 {
+				assert.GreaterOrEqual(t, need, tc.minNeed)
+				assert.Greater(t, need, len(tc.input))
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI is a throwaway certificate authority and the files a server
+// needs to use it.
+type testPKI struct {
+	t      *testing.T
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	pool   *x509.CertPool
+	client tls.Certificate
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "goredis test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	p := &testPKI{t: t, dir: t.TempDir(), ca: ca, caKey: key, pool: x509.NewCertPool(), serial: 1}
+	p.pool.AddCert(ca)
+	p.write("ca.crt", "CERTIFICATE", der)
+
+	certPEM, keyPEM := p.issue(x509.ExtKeyUsageClientAuth)
+	p.client, err = tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	p.issueServer()
+	return p
+}
+
+// issueServer writes a new server certificate and key and returns the
+// certificate's serial number.
+func (p *testPKI) issueServer() int64 {
+	certPEM, keyPEM := p.issue(x509.ExtKeyUsageServerAuth)
+	require.NoError(p.t, os.WriteFile(p.path("server.crt"), certPEM, 0o600))
+	require.NoError(p.t, os.WriteFile(p.path("server.key"), keyPEM, 0o600))
+	return p.serial
+}
+
+func (p *testPKI) issue(usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(p.t, err)
+	p.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: "goredis"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	require.NoError(p.t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(p.t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (p *testPKI) write(name, typ string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	require.NoError(p.t, os.WriteFile(p.path(name), data, 0o600))
+}
+
+func (p *testPKI) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+// configure enables a TLS listener that uses p's certificates.
+func (p *testPKI) configure(port int) func(*config.Config) {
+	return func(c *config.Config) {
+		c.TLSPort = port
+		c.TLSCertFile = p.path("server.crt")
+		c.TLSKeyFile = p.path("server.key")
+		c.TLSCACertFile = p.path("ca.crt")
+	}
+}
+
+// clientConfig trusts p's CA and presents the client certificate if
+// withCert is set.
+func (p *testPKI) clientConfig(withCert bool) *tls.Config {
+	tc := &tls.Config{RootCAs: p.pool}
+	if withCert {
+		tc.Certificates = []tls.Certificate{p.client}
+	}
+	return tc
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	return port
+}
+
+// dialTLS connects to addr and completes the handshake.
+func dialTLS(t *testing.T, addr string, tc *tls.Config) (*testConn, error) {
+	t.Helper()
+	var conn *tls.Conn
+	var err error
+	// The TLS listener may open just after the plaintext one.
+	for range 50 {
+		if conn, err = tls.Dial("tcp", addr, tc); err == nil {
+			break
+		}
+		var opErr *net.OpError
+		if !errors.As(err, &opErr) || opErr.Op != "dial" {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck // OK for testing
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	return &testConn{t: t, conn: conn, reader: resp.NewReader(conn)}, nil
+}
+
+// pingTLS reports whether a TLS client configured with tc can run PING.
+func pingTLS(t *testing.T, addr string, tc *tls.Config) error {
+	t.Helper()
+	conn, err := dialTLS(t, addr, tc)
+	if err != nil {
+		return err
+	}
+	// With TLS 1.3 a rejected client certificate surfaces on first use.
+	if _, err := conn.conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+	reply, err := conn.reader.ReadValue()
+	if err != nil {
+		return err
+	}
+	require.Equal(t, "PONG", reply.String)
+	return nil
+}
+
+func TestTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	port := freePort(t)
+	addr := startServer(t, pki.configure(port))
+	tlsAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	admin := dial(t, addr)
+
+	t.Run("plaintext and TLS together", func(t *testing.T) {
+		assert.Equal(t, "PONG", admin.send("PING\r\n").String)
+		assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(true)))
+	})
+
+	t.Run("client certificate required", func(t *testing.T) {
+		assert.Error(t, pingTLS(t, tlsAddr, pki.clientConfig(false)))
+
+		untrusted := pki.clientConfig(false)
+		untrusted.RootCAs = x509.NewCertPool()
+		assert.Error(t, pingTLS(t, tlsAddr, untrusted), "server certificate is verified")
+	})
+
+	t.Run("optional client certificate", func(t *testing.T) {
+		require.Equal(t, resp.OK, admin.send("CONFIG SET tls-auth-clients optional\r\n"))
+		t.Cleanup(func() { admin.send("CONFIG SET tls-auth-clients yes\r\n") })
+		assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(false)))
+		assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(true)))
+	})
+
+	t.Run("certificates reload on CONFIG SET", func(t *testing.T) {
+		old, err := dialTLS(t, tlsAddr, pki.clientConfig(true))
+		require.NoError(t, err)
+
+		serial := pki.issueServer()
+		reply := admin.send("CONFIG SET tls-cert-file " + pki.path("server.crt") + " tls-key-file " + pki.path("server.key") + "\r\n")
+		require.Equal(t, resp.OK, reply)
+
+		conn, err := dialTLS(t, tlsAddr, pki.clientConfig(true))
+		require.NoError(t, err)
+		peer := conn.conn.(*tls.Conn).ConnectionState().PeerCertificates[0]
+		assert.Equal(t, serial, peer.SerialNumber.Int64())
+		assert.Equal(t, "PONG", old.send("PING\r\n").String, "existing connections are kept")
+	})
+
+	t.Run("failed reload keeps the configuration", func(t *testing.T) {
+		reply := admin.send("CONFIG SET tls-cert-file " + pki.path("missing.crt") + "\r\n")
+		assert.Equal(t, "ERR CONFIG SET failed (possibly related to argument 'tls-cert-file') - Unable to update TLS configuration. Check server logs.", reply.String)
+
+		reply = admin.send("CONFIG SET tls-protocols TLSv9\r\n")
+		assert.Equal(t, resp.SimpleError, reply.Type)
+
+		got := admin.send("CONFIG GET tls-cert-file\r\n")
+		require.Len(t, got.Array, 2)
+		assert.Equal(t, pki.path("server.crt"), got.Array[1].String)
+		assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(true)))
+	})
+
+	t.Run("protocol versions", func(t *testing.T) {
+		require.Equal(t, resp.OK, admin.send("CONFIG SET tls-protocols TLSv1.3\r\n"))
+		t.Cleanup(func() { admin.send("CONFIG SET tls-protocols \"\"\r\n") })
+
+		tls12 := pki.clientConfig(true)
+		tls12.MaxVersion = tls.VersionTLS12
+		assert.Error(t, pingTLS(t, tlsAddr, tls12))
+		assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(true)))
+	})
+}
+
+func TestTLSWithReactor(t *testing.T) {
+	pki := newTestPKI(t)
+	port := freePort(t)
+	addr := startServer(t, pki.configure(port), withIOModel(config.ReactorIO, 1))
+
+	tlsAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	assert.Equal(t, "PONG", dial(t, addr).send("PING\r\n").String)
+	assert.NoError(t, pingTLS(t, tlsAddr, pki.clientConfig(true)))
+
+	// TLS clients run on their own goroutines and reach the reactor's
+	// clients through pushes and closes; run with -race to check them.
+	t.Run("cross-client pushes and closes", func(t *testing.T) {
+		tlsConn, err := dialTLS(t, tlsAddr, pki.clientConfig(true))
+		require.NoError(t, err)
+
+		const subs = 4
+		var subscribers []*testConn
+		for i := range subs {
+			sub := dial(t, addr)
+			sub.send(fmt.Sprintf("CLIENT SETNAME sub%d\r\n", i))
+			sub.send("SUBSCRIBE news\r\n")
+			subscribers = append(subscribers, sub)
+		}
+
+		tracker := dial(t, addr)
+		tracker.send("HELLO 3\r\n")
+		require.Equal(t, resp.OK, tracker.send("CLIENT TRACKING on\r\n"))
+		tracker.send("GET tracked\r\n")
+
+		bob := dial(t, addr)
+		require.Equal(t, resp.OK, tlsConn.send("ACL SETUSER bob on >pw ~* +@all\r\n"))
+		require.Equal(t, resp.OK, bob.send("AUTH bob pw\r\n"))
+
+		// Reactor and TLS clients publish at the same time.
+		const n = 50
+		plainPub := dial(t, addr)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range n {
+				plainPub.send("PUBLISH news plain\r\n")
+			}
+		}()
+		for range n {
+			assert.Equal(t, int64(subs), tlsConn.send("PUBLISH news tls\r\n").Integer)
+			assert.Contains(t, tlsConn.send("CLIENT LIST\r\n").String, "name=sub0")
+		}
+		wg.Wait()
+		for _, sub := range subscribers {
+			for range 2 * n {
+				require.Len(t, sub.read().Array, 3)
+			}
+		}
+
+		require.Equal(t, resp.OK, tlsConn.send("SET tracked v\r\n"))
+		push := tracker.read()
+		assert.Equal(t, resp.Push, push.Type)
+		assert.Equal(t, "invalidate", push.Array[0].String)
+
+		assert.Equal(t, int64(1), tlsConn.send("ACL DELUSER bob\r\n").Integer)
+		_, err = bob.reader.ReadValue()
+		assert.Error(t, err, "deleting the user closes its connections")
+	})
+}
+
+func TestTLSBadCertificateStopsStartup(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.Bind = []string{"127.0.0.1"}
+	conf.Port = freePort(t)
+	conf.TLSPort = freePort(t)
+	conf.TLSCertFile = filepath.Join(conf.Dir, "missing.crt")
+	conf.TLSKeyFile = filepath.Join(conf.Dir, "missing.key")
+	assert.Error(t, server.NewServer().Start(db.NewAppState(conf)))
+}
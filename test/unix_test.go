@@ -0,0 +1,98 @@
+package test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shivakuppa/Go_Redis/config"
+	"github.com/shivakuppa/Go_Redis/internals/db"
+	"github.com/shivakuppa/Go_Redis/internals/resp"
+	"github.com/shivakuppa/Go_Redis/internals/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withUnixSocket(path string, perm os.FileMode) func(*config.Config) {
+	return func(c *config.Config) {
+		c.UnixSocket = path
+		c.UnixSocketPerm = perm
+	}
+}
+
+func dialUnix(t *testing.T, path string) *testConn {
+	t.Helper()
+
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		var err error
+		conn, err = net.Dial("unix", path)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	t.Cleanup(func() { conn.Close() }) //nolint:errcheck // OK for testing
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return &testConn{t: t, conn: conn, reader: resp.NewReader(conn)}
+}
+
+func TestUnixSocket(t *testing.T) {
+	for _, model := range []config.IOModel{config.GoroutineIO, config.ReactorIO} {
+		t.Run(string(model), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "redis.sock")
+			addr := startServer(t, withUnixSocket(path, 0o700), withIOModel(model, 1), withPassword("secret"))
+
+			fi, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o700), fi.Mode().Perm())
+
+			unix := dialUnix(t, path)
+			assert.Equal(t, "NOAUTH Authentication required.", unix.send("PING\r\n").String)
+			require.Equal(t, resp.OK, unix.send("AUTH secret\r\n"))
+			assert.Equal(t, resp.OK, unix.send("SET k v\r\n"))
+
+			info := unix.send("CLIENT INFO\r\n").String
+			assert.Contains(t, info, " addr="+path+":0 laddr="+path+":0 ")
+			assert.Contains(t, info, " flags=U ")
+
+			tcp := dial(t, addr)
+			require.Equal(t, resp.OK, tcp.send("AUTH secret\r\n"))
+			assert.Equal(t, "v", tcp.send("GET k\r\n").String)
+			list := tcp.send("CLIENT LIST\r\n").String
+			assert.Contains(t, list, " addr="+path+":0 ")
+			assert.Contains(t, list, " flags=N ")
+		})
+	}
+}
+
+func TestUnixSocketOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	// A socket file left behind by an earlier run is replaced.
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+
+	conf := config.NewConfig()
+	conf.Dir = t.TempDir()
+	conf.RDBfn = "dump.rdb"
+	conf.Port = 0
+	conf.UnixSocket = path
+	done := make(chan error, 1)
+	go func() {
+		done <- server.NewServer().Start(db.NewAppState(conf))
+	}()
+
+	conn := dialUnix(t, path)
+	assert.Equal(t, "PONG", conn.send("PING\r\n").String)
+
+	_, err = conn.conn.Write([]byte("SHUTDOWN NOSAVE\r\n"))
+	require.NoError(t, err)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	assert.NoFileExists(t, path, "the socket is removed on shutdown")
+}